@@ -1,11 +1,14 @@
 module github.com/xiaorui77/goutils
 
-go 1.17
+go 1.21
 
-require github.com/olivere/elastic/v7 v7.0.31
+require (
+	github.com/golang/snappy v0.0.4
+	github.com/olivere/elastic/v7 v7.0.31
+	github.com/pkg/errors v0.9.1
+)
 
 require (
 	github.com/josharian/intern v1.0.0 // indirect
 	github.com/mailru/easyjson v0.7.7 // indirect
-	github.com/pkg/errors v0.9.1 // indirect
 )
@@ -1,8 +1,10 @@
 package httpr
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/xiaorui77/goutils/logx"
 	"github.com/xiaorui77/goutils/math"
 	"net"
 	"net/http"
@@ -37,6 +39,10 @@ func NewContext(w http.ResponseWriter, r *http.Request) *Context {
 	} else {
 		c.RequestId = generateRequestId(r.RemoteAddr)
 	}
+
+	// request-scoped entry, use logx.Ctx(c.Request.Context()) in handlers
+	ctx := context.WithValue(r.Context(), logx.RequestIdKey, c.RequestId)
+	c.Request = r.WithContext(logx.NewContext(ctx, logx.WithField("requestId", c.RequestId)))
 	return c
 }
 
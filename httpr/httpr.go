@@ -34,6 +34,13 @@ func (e *Httpr) DELETE(pattern string, handler HandlerFunc) {
 	e.addRoute(http.MethodDelete, pattern, handler)
 }
 
+// Handle mounts a standard http.Handler, e.g. logx.LevelHandler.
+func (e *Httpr) Handle(method, pattern string, handler http.Handler) {
+	e.addRoute(method, pattern, func(c *Context) {
+		handler.ServeHTTP(c.Writer, c.Request)
+	})
+}
+
 func (e *Httpr) addRoute(method, pattern string, handler HandlerFunc) {
 	logx.Infof("[httpr] Route register: %s - %s", method, pattern)
 	e.router.registerRoute(method, pattern, handler)
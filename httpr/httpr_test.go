@@ -3,6 +3,7 @@ package httpr
 import (
 	"github.com/xiaorui77/goutils/logx"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -35,3 +36,28 @@ func TestGenerateRequestId(t *testing.T) {
 	requestId := generateRequestId("192.168.2.1:1234")
 	t.Skipf("%s", requestId)
 }
+
+func TestContextLogger(t *testing.T) {
+	var entry *logx.Entry
+	router := NewEngine()
+	router.GET("/ctx", func(c *Context) {
+		entry = logx.Ctx(c.Request.Context())
+	})
+	req := httptest.NewRequest(http.MethodGet, "/ctx", nil)
+	req.Header.Set("x-request-id", "r-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if entry == nil || entry.Fields["requestId"] != "r-1" {
+		t.Errorf("expected request-scoped entry with requestId, actual %v", entry)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	router := NewEngine()
+	router.Handle(http.MethodGet, "/level", logx.LevelHandler(logx.NewLogx("test")))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/level", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("expected mounted handler, actual status %d", w.Code)
+	}
+}
@@ -0,0 +1,164 @@
+package logx
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what AsyncWriter does when the queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the logging goroutine until there is space.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the entry being written.
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest queued entry to make room.
+	OverflowDropOldest
+)
+
+// AsyncWriter is an io.Writer which copies formatted entries into a bounded ring buffer,
+// a background goroutine drains them into the underlying writer.
+type AsyncWriter struct {
+	out    io.Writer
+	policy OverflowPolicy
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	ring    [][]byte
+	head    int
+	count   int
+	writing bool
+	closed  bool
+	done    chan struct{}
+
+	written uint64
+	dropped uint64
+}
+
+func NewAsyncWriter(out io.Writer, size int, policy OverflowPolicy) *AsyncWriter {
+	if size <= 0 {
+		size = 1024
+	}
+	w := &AsyncWriter{
+		out:    out,
+		policy: policy,
+		ring:   make([][]byte, size),
+		done:   make(chan struct{}),
+	}
+	w.cond = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+// Write queues a copy of p, it returns os.ErrClosed once the writer is closed.
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	data := make([]byte, len(p))
+	copy(data, p)
+
+	w.mu.Lock()
+	for !w.closed && w.count == len(w.ring) {
+		switch w.policy {
+		case OverflowDropNewest:
+			w.mu.Unlock()
+			atomic.AddUint64(&w.dropped, 1)
+			return len(p), nil
+		case OverflowDropOldest:
+			w.ring[w.head] = nil
+			w.head = (w.head + 1) % len(w.ring)
+			w.count--
+			atomic.AddUint64(&w.dropped, 1)
+		default:
+			w.cond.Wait()
+		}
+	}
+	if w.closed {
+		w.mu.Unlock()
+		return 0, os.ErrClosed
+	}
+
+	w.ring[(w.head+w.count)%len(w.ring)] = data
+	w.count++
+	w.cond.Broadcast()
+	w.mu.Unlock()
+	return len(p), nil
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	for {
+		w.mu.Lock()
+		for w.count == 0 && !w.closed {
+			w.cond.Wait()
+		}
+		if w.count == 0 {
+			w.mu.Unlock()
+			return
+		}
+		data := w.ring[w.head]
+		w.ring[w.head] = nil
+		w.head = (w.head + 1) % len(w.ring)
+		w.count--
+		w.writing = true
+		w.cond.Broadcast()
+		w.mu.Unlock()
+
+		if _, err := w.out.Write(data); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Failed to write to Output, %v\n", err)
+		}
+		atomic.AddUint64(&w.written, 1)
+
+		w.mu.Lock()
+		w.writing = false
+		w.cond.Broadcast()
+		w.mu.Unlock()
+	}
+}
+
+// Flush blocks until all queued entries are written.
+func (w *AsyncWriter) Flush() error {
+	w.mu.Lock()
+	for w.count > 0 || w.writing {
+		w.cond.Wait()
+	}
+	w.mu.Unlock()
+	return nil
+}
+
+// Close writes the remaining entries, stops the background goroutine and closes the underlying writer
+// if it is an io.Closer other than os.Stdout and os.Stderr. It is safe to call Close more than once.
+func (w *AsyncWriter) Close() error {
+	if !w.stop() || w.out == os.Stdout || w.out == os.Stderr {
+		return nil
+	}
+	if c, ok := w.out.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// stop writes the remaining entries and stops the background goroutine without closing the underlying writer,
+// it reports whether the writer was open.
+func (w *AsyncWriter) stop() bool {
+	w.mu.Lock()
+	closed := w.closed
+	w.closed = true
+	w.cond.Broadcast()
+	w.mu.Unlock()
+
+	<-w.done
+	return !closed
+}
+
+// Written returns the number of entries written to the underlying writer.
+func (w *AsyncWriter) Written() uint64 {
+	return atomic.LoadUint64(&w.written)
+}
+
+// Dropped returns the number of entries discarded by the overflow policy.
+func (w *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
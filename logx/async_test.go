@@ -0,0 +1,110 @@
+package logx
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+)
+
+// gateWriter blocks the first write until released.
+type gateWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	entered chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{entered: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() {
+		close(w.entered)
+		<-w.release
+	})
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gateWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncWriterOverflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   OverflowPolicy
+		expected string
+	}{
+		{"drop newest", OverflowDropNewest, "012"},
+		{"drop oldest", OverflowDropOldest, "034"},
+	}
+
+	for _, test := range tests {
+		out := newGateWriter()
+		w := NewAsyncWriter(out, 2, test.policy)
+		_, _ = w.Write([]byte("0"))
+		<-out.entered
+		for _, s := range []string{"1", "2", "3", "4"} {
+			_, _ = w.Write([]byte(s))
+		}
+		close(out.release)
+		_ = w.Flush()
+
+		if actual := out.String(); actual != test.expected {
+			t.Errorf("Test %s: expected %q, actual %q", test.name, test.expected, actual)
+		}
+		if w.Dropped() != 2 {
+			t.Errorf("Test %s: expected 2 dropped, actual %d", test.name, w.Dropped())
+		}
+		_ = w.Close()
+	}
+}
+
+func TestAsyncLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := NewLogx("test", WithOutput(&buffer), WithAsync(16, OverflowBlock))
+	for i := 0; i < 100; i++ {
+		logger.Infof("hello %d", i)
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if lines := bytes.Count(buffer.Bytes(), []byte("\n")); lines != 100 {
+		t.Errorf("expected 100 lines after Close, actual %d", lines)
+	}
+}
+
+// closeCounter counts the calls of Close.
+type closeCounter struct {
+	bytes.Buffer
+	closed int
+}
+
+func (c *closeCounter) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestAsyncWriterClose(t *testing.T) {
+	out := &closeCounter{}
+	w := NewAsyncWriter(out, 4, OverflowBlock)
+	_, _ = w.Write([]byte("queued\n"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	_ = w.Close()
+	if out.String() != "queued\n" || out.closed != 1 {
+		t.Errorf("expected queued entry written and the writer closed once, actual %q closed %d", out.String(), out.closed)
+	}
+	if _, err := w.Write([]byte("after close\n")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("expected os.ErrClosed after Close, actual %v", err)
+	}
+}
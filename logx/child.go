@@ -0,0 +1,118 @@
+package logx
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Named returns a child logger named as "parent.name", e.g. logx.Named("db").Named("pool") is "app.db.pool".
+// The child shares the output, formatter and hooks of the parent and inherits the level
+// until SetLevel is called on it, later changes of the parent are visible to the child.
+func (l *LogX) Named(name string) *LogX {
+	child := l.newChild()
+	if l.Name != "" && name != "" {
+		child.Name = l.Name + "." + name
+	} else {
+		child.Name = l.Name + name
+	}
+	return child
+}
+
+// With returns a child logger which adds the fields to every entry.
+func (l *LogX) With(fields Fields) *LogX {
+	child := l.newChild()
+	child.fields = make(Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		child.fields[k] = v
+	}
+	for k, v := range fields {
+		child.fields[k] = v
+	}
+	return child
+}
+
+func (l *LogX) newChild() *LogX {
+	child := &LogX{
+		Name:       l.Name,
+		Instance:   l.Instance,
+		modules:    l.modules,
+		parent:     l,
+		fields:     l.fields,
+		mu:         l.mu,
+		generation: l.generation,
+		sampler:    l.sampler,
+		deduper:    l.deduper,
+	}
+	// the formatter and output are inherited while they are nil
+	child.settings.Store(&settings{reportCaller: l.loadSettings().reportCaller})
+	child.entryPool = &sync.Pool{
+		New: func() interface{} {
+			return NewEntry(child)
+		},
+	}
+	return child
+}
+
+// GetLevel returns the level of the logger: the module override matching its name,
+// otherwise its own level, children inherit the level from their parent unless overridden.
+func (l *LogX) GetLevel() Level {
+	if level, ok := l.moduleLevel(); ok {
+		return level
+	}
+	if l.parent != nil && atomic.LoadInt32(&l.levelSet) == 0 {
+		return l.parent.GetLevel()
+	}
+	return Level(atomic.LoadInt32(&l.level))
+}
+
+// settings is replaced as a whole on change, so an entry which loads it once
+// sees the formatter, output and caller reporting of a single configuration.
+type settings struct {
+	formatter    Formatter
+	out          io.Writer
+	reportCaller bool
+}
+
+func (l *LogX) loadSettings() *settings {
+	s, _ := l.settings.Load().(*settings)
+	return s
+}
+
+// updateSettings stores a modified copy of the settings, the caller holds l.mu.
+func (l *LogX) updateSettings(update func(s *settings)) {
+	s := *l.loadSettings()
+	update(&s)
+	l.storeSettings(&s)
+}
+
+// storeSettings replaces the settings, the caller holds l.mu.
+func (l *LogX) storeSettings(s *settings) {
+	l.settings.Store(s)
+	atomic.AddUint64(l.generation, 1)
+}
+
+// effectiveSettings returns the settings of the logger with the formatter
+// and output of the nearest ancestor which has them.
+func (l *LogX) effectiveSettings() settings {
+	s := *l.loadSettings()
+	for p := l.parent; p != nil && (s.formatter == nil || s.out == nil); p = p.parent {
+		ps := p.loadSettings()
+		if s.formatter == nil {
+			s.formatter = ps.formatter
+		}
+		if s.out == nil {
+			s.out = ps.out
+		}
+	}
+	return s
+}
+
+// output returns the writer of the logger or the nearest ancestor which has one.
+func (l *LogX) output() io.Writer {
+	return l.effectiveSettings().out
+}
+
+func (l *LogX) formatter() Formatter {
+	return l.effectiveSettings().formatter
+}
@@ -0,0 +1,44 @@
+package logx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestChildLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	root := NewLogx("app", WithOutput(&buffer), WithFormatter(NewLogfmtFormatter()))
+	db := root.Named("db")
+	pool := db.Named("pool").With(Fields{"shard": 1})
+
+	if pool.Name != "app.db.pool" {
+		t.Errorf("expected dotted name app.db.pool, actual %s", pool.Name)
+	}
+
+	pool.Info("connected")
+	if line := buffer.String(); !strings.Contains(line, "name=app.db.pool") || !strings.Contains(line, "shard=1") {
+		t.Errorf("expected child name and fields in %q", line)
+	}
+
+	// level changes of the parent propagate until the child overrides it
+	buffer.Reset()
+	root.SetLevel(DebugLevel)
+	pool.Debug("debug enabled")
+	db.SetLevel(WarnLevel)
+	pool.Info("hidden")
+	root.SetLevel(InfoLevel)
+	db.Warn("db warn")
+	if actual := buffer.String(); !strings.Contains(actual, "debug enabled") || strings.Contains(actual, "hidden") ||
+		!strings.Contains(actual, "db warn") {
+		t.Errorf("unexpected level inheritance, output %q", actual)
+	}
+
+	// hooks added to the parent later are fired by children
+	hook := &captureHook{}
+	root.AddHook(hook)
+	pool.Warn("hooked")
+	if messages := hook.messages(); len(messages) != 1 || messages[0] != "hooked" {
+		t.Errorf("expected hook of parent fired by child, actual %v", messages)
+	}
+}
@@ -0,0 +1,426 @@
+package logx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/xiaorui77/goutils/logx/rotate"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config is the json configuration of a logger, e.g.
+//
+//	{
+//	  "level": "info",
+//	  "modules": {"app.db.*": "debug"},
+//	  "reportCaller": true,
+//	  "formatter": {"type": "json", "timestampFormat": "2006-01-02T15:04:05.999Z07:00"},
+//	  "outputs": [
+//	    {"type": "stdout"},
+//	    {"type": "file", "path": "/var/log/app.log", "rotate": {"maxSize": 104857600, "schedule": "daily", "maxAge": "168h", "compress": true}}
+//	  ],
+//	  "hooks": [{"type": "elasticsearch", "options": {"url": "http://127.0.0.1:9200"}}]
+//	}
+type Config struct {
+	Level        string            `json:"level"`
+	Modules      map[string]string `json:"modules"`
+	ReportCaller bool              `json:"reportCaller"`
+	Formatter    FormatterConfig   `json:"formatter"`
+	Outputs      []OutputConfig    `json:"outputs"`
+	Hooks        []HookConfig      `json:"hooks"`
+}
+
+type FormatterConfig struct {
+	// Type is one of text, json, logfmt and pattern, defaults to text.
+	Type string `json:"type"`
+	// Colorful is used by text and pattern, defaults to true.
+	Colorful *bool `json:"colorful"`
+	// Pattern is the layout of PatternFormatter.
+	Pattern string `json:"pattern"`
+	// TimestampFormat is used by json and logfmt.
+	TimestampFormat string `json:"timestampFormat"`
+	// FieldsKey is used by json.
+	FieldsKey string `json:"fieldsKey"`
+}
+
+type OutputConfig struct {
+	// Type is one of stdout, stderr and file.
+	Type   string        `json:"type"`
+	Path   string        `json:"path"`
+	Rotate *RotateConfig `json:"rotate"`
+}
+
+type RotateConfig struct {
+	MaxSize int64 `json:"maxSize"`
+	// Schedule is one of hourly and daily.
+	Schedule string `json:"schedule"`
+	// MaxAge is a duration string, e.g. "168h".
+	MaxAge     string `json:"maxAge"`
+	MaxBackups int    `json:"maxBackups"`
+	Compress   bool   `json:"compress"`
+}
+
+type HookConfig struct {
+	Type    string          `json:"type"`
+	Options json.RawMessage `json:"options"`
+}
+
+// HookFactory creates a hook by the options of HookConfig.
+type HookFactory func(options json.RawMessage) (Hook, error)
+
+var (
+	hookFactoriesMu sync.RWMutex
+	hookFactories   = map[string]HookFactory{}
+)
+
+// RegisterHookFactory makes a hook type available to config files.
+func RegisterHookFactory(typ string, factory HookFactory) {
+	hookFactoriesMu.Lock()
+	defer hookFactoriesMu.Unlock()
+	hookFactories[typ] = factory
+}
+
+// ParseConfig decodes the json config, unknown keys are rejected.
+func ParseConfig(data []byte) (*Config, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	cfg := &Config{}
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("logx: config: %v", err)
+	}
+	return cfg, nil
+}
+
+// configState holds the resources created by the config, they are released on the next apply.
+type configState struct {
+	hooks   []Hook
+	closers []io.Closer
+}
+
+// built is a validated config which is ready to be applied.
+type built struct {
+	level        Level
+	modules      map[string]Level
+	reportCaller bool
+	formatter    Formatter
+	out          io.Writer
+	hooks        []Hook
+	closers      []io.Closer
+}
+
+// close releases the outputs and hooks of a config which is not applied.
+func (b *built) close() {
+	for _, c := range b.closers {
+		_ = c.Close()
+	}
+	for _, hook := range b.hooks {
+		closeHook(hook)
+	}
+}
+
+// hookCloseTimeout bounds closing a hook which takes a context, e.g. hooks.EsHook.
+var hookCloseTimeout = 5 * time.Second
+
+type contextCloser interface {
+	Close(ctx context.Context) error
+}
+
+// closeHook releases the goroutines and connections of a hook created by a factory,
+// if it has a Close method with or without a context.
+func closeHook(hook Hook) {
+	var err error
+	switch c := hook.(type) {
+	case io.Closer:
+		err = c.Close()
+	case contextCloser:
+		ctx, cancel := context.WithTimeout(context.Background(), hookCloseTimeout)
+		err = c.Close(ctx)
+		cancel()
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to close hook %T, %v\n", hook, err)
+	}
+}
+
+func (l *LogX) build(cfg *Config) (*built, error) {
+	b := &built{level: InfoLevel, modules: map[string]Level{}, reportCaller: cfg.ReportCaller}
+
+	if cfg.Level != "" {
+		level, ok := LookupLevel(cfg.Level)
+		if !ok {
+			return nil, fmt.Errorf("logx: config: unknown level %q", cfg.Level)
+		}
+		b.level = level
+	}
+	for pattern, name := range cfg.Modules {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("logx: config: modules: invalid pattern %q: %v", pattern, err)
+		}
+		level, ok := LookupLevel(name)
+		if !ok {
+			return nil, fmt.Errorf("logx: config: modules: unknown level %q of %q", name, pattern)
+		}
+		b.modules[pattern] = level
+	}
+
+	formatter, err := buildFormatter(l, cfg.Formatter)
+	if err != nil {
+		return nil, fmt.Errorf("logx: config: formatter: %v", err)
+	}
+	b.formatter = formatter
+
+	var writers []io.Writer
+	outputs := cfg.Outputs
+	if len(outputs) == 0 {
+		outputs = []OutputConfig{{Type: "stdout"}}
+	}
+	for i, o := range outputs {
+		w, err := buildOutput(o)
+		if err != nil {
+			b.close()
+			return nil, fmt.Errorf("logx: config: outputs[%d]: %v", i, err)
+		}
+		if c, ok := w.(io.Closer); ok && w != os.Stdout && w != os.Stderr {
+			b.closers = append(b.closers, c)
+		}
+		writers = append(writers, w)
+	}
+	if len(writers) == 1 {
+		b.out = writers[0]
+	} else {
+		b.out = io.MultiWriter(writers...)
+	}
+
+	for i, h := range cfg.Hooks {
+		hookFactoriesMu.RLock()
+		factory, ok := hookFactories[h.Type]
+		hookFactoriesMu.RUnlock()
+		if !ok {
+			b.close()
+			return nil, fmt.Errorf("logx: config: hooks[%d]: unknown type %q", i, h.Type)
+		}
+		hook, err := factory(h.Options)
+		if err != nil {
+			b.close()
+			return nil, fmt.Errorf("logx: config: hooks[%d]: %v", i, err)
+		}
+		b.hooks = append(b.hooks, hook)
+	}
+	return b, nil
+}
+
+func buildFormatter(l *LogX, cfg FormatterConfig) (Formatter, error) {
+	colorful := cfg.Colorful == nil || *cfg.Colorful
+	switch strings.ToLower(cfg.Type) {
+	case "", "text":
+		return NewTextFormatter(l, colorful), nil
+	case "json":
+		f := NewJSONFormatter()
+		if cfg.TimestampFormat != "" {
+			f.TimestampFormat = cfg.TimestampFormat
+		}
+		f.FieldsKey = cfg.FieldsKey
+		return f, nil
+	case "logfmt":
+		f := NewLogfmtFormatter()
+		if cfg.TimestampFormat != "" {
+			f.TimestampFormat = cfg.TimestampFormat
+		}
+		return f, nil
+	case "pattern":
+		if cfg.Pattern == "" {
+			return nil, fmt.Errorf("pattern is required for pattern formatter")
+		}
+		return NewPatternFormatter(cfg.Pattern, colorful)
+	}
+	return nil, fmt.Errorf("unknown type %q", cfg.Type)
+}
+
+func buildOutput(cfg OutputConfig) (io.Writer, error) {
+	switch strings.ToLower(cfg.Type) {
+	case "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	case "file":
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("path is required for file output")
+		}
+		var opts []rotate.Option
+		if r := cfg.Rotate; r != nil {
+			switch strings.ToLower(r.Schedule) {
+			case "":
+			case "hourly":
+				opts = append(opts, rotate.WithSchedule(rotate.Hourly))
+			case "daily":
+				opts = append(opts, rotate.WithSchedule(rotate.Daily))
+			default:
+				return nil, fmt.Errorf("unknown rotate schedule %q", r.Schedule)
+			}
+			if r.MaxAge != "" {
+				maxAge, err := time.ParseDuration(r.MaxAge)
+				if err != nil {
+					return nil, fmt.Errorf("invalid rotate maxAge %q: %v", r.MaxAge, err)
+				}
+				opts = append(opts, rotate.WithMaxAge(maxAge))
+			}
+			opts = append(opts, rotate.WithMaxSize(r.MaxSize), rotate.WithMaxBackups(r.MaxBackups),
+				rotate.WithCompress(r.Compress))
+		}
+		return rotate.New(cfg.Path, opts...)
+	}
+	return nil, fmt.Errorf("unknown type %q", cfg.Type)
+}
+
+// ApplyConfig validates the config and reconfigures the logger atomically,
+// nothing is changed if the config is invalid. Outputs and hooks created by the
+// previous config are replaced, the files are closed once no entry is being written to them
+// and the hooks are closed once their queued entries are fired.
+func (l *LogX) ApplyConfig(cfg *Config) error {
+	b, err := l.build(cfg)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	old := l.config
+	l.storeSettings(&settings{formatter: b.formatter, out: b.out, reportCaller: b.reportCaller})
+	l.SetLevel(b.level)
+	l.modules.replace(b.modules)
+	var removed []*registeredHook
+	if old != nil {
+		removed = l.removeHooksLocked(old.hooks)
+	}
+	for _, hook := range b.hooks {
+		l.addHookLocked(hook)
+	}
+	l.config = &configState{hooks: b.hooks, closers: b.closers}
+	l.mu.Unlock()
+
+	for _, h := range removed {
+		h.stop()
+	}
+	if old != nil {
+		for _, c := range old.closers {
+			_ = c.Close()
+		}
+		for _, hook := range old.hooks {
+			closeHook(hook)
+		}
+	}
+	return nil
+}
+
+// ConfigLoader reloads the config file on demand or when it changes.
+type ConfigLoader struct {
+	path   string
+	logger *LogX
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// LoadConfig reads the json config file and applies it to the std logger.
+func LoadConfig(path string) (*ConfigLoader, error) {
+	return std.LoadConfig(path)
+}
+
+// LoadConfig reads the json config file and applies it to the logger.
+func (l *LogX) LoadConfig(path string) (*ConfigLoader, error) {
+	c := &ConfigLoader{path: path, logger: l, stop: make(chan struct{})}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload reads and applies the config file, the running config is kept if it is invalid.
+func (c *ConfigLoader) Reload() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return fmt.Errorf("logx: config: %v", err)
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("logx: config: %v", err)
+	}
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return err
+	}
+	if err := c.logger.ApplyConfig(cfg); err != nil {
+		return err
+	}
+	c.modTime, c.size = info.ModTime(), info.Size()
+	return nil
+}
+
+// Watch checks the file every interval and reloads it when changed, errors are written to stderr.
+func (c *ConfigLoader) Watch(interval time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done != nil {
+		return
+	}
+	c.done = make(chan struct{})
+
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				if !c.changed() {
+					continue
+				}
+				if err := c.Reload(); err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "Failed to reload config, %v\n", err)
+					c.mu.Lock()
+					// don't retry until the file changes again
+					if info, err := os.Stat(c.path); err == nil {
+						c.modTime, c.size = info.ModTime(), info.Size()
+					}
+					c.mu.Unlock()
+				}
+			}
+		}
+	}()
+}
+
+func (c *ConfigLoader) changed() bool {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !info.ModTime().Equal(c.modTime) || info.Size() != c.size
+}
+
+// Close stops watching, the logger keeps the current config.
+func (c *ConfigLoader) Close() {
+	c.stopOnce.Do(func() { close(c.stop) })
+	c.mu.Lock()
+	done := c.done
+	c.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
@@ -0,0 +1,207 @@
+package logx
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, path, content string) {
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logx.json")
+	logPath := filepath.Join(dir, "app.log")
+	writeConfig(t, path, `{
+		"level": "debug",
+		"modules": {"app.db.*": "error"},
+		"reportCaller": true,
+		"formatter": {"type": "json"},
+		"outputs": [{"type": "file", "path": "`+logPath+`", "rotate": {"maxSize": 1048576, "schedule": "daily"}}]
+	}`)
+
+	logger := NewLogx("app")
+	loader, err := logger.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	defer loader.Close()
+
+	if logger.GetLevel() != DebugLevel || logger.Named("db").Named("pool").GetLevel() != ErrorLevel {
+		t.Errorf("expected levels of the config")
+	}
+	logger.Debug("to file")
+
+	// invalid config is rejected as a whole
+	writeConfig(t, path, `{"level": "info", "formatter": {"type": "xml"}}`)
+	if err := loader.Reload(); err == nil || !strings.Contains(err.Error(), `formatter: unknown type "xml"`) {
+		t.Errorf("expected descriptive error, actual %v", err)
+	}
+	if logger.GetLevel() != DebugLevel {
+		t.Errorf("expected running config kept after invalid reload")
+	}
+
+	writeConfig(t, path, `{"level": "warn", "formatter": {"type": "logfmt"}, "outputs": [{"type": "stderr"}]}`)
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("Reload() error: %v", err)
+	}
+	if logger.GetLevel() != WarnLevel || logger.GetOutput() != os.Stderr || len(logger.ModuleLevels()) != 0 {
+		t.Errorf("expected config reloaded")
+	}
+
+	data, _ := os.ReadFile(logPath)
+	var line map[string]interface{}
+	if err := json.Unmarshal(data, &line); err != nil || line["msg"] != "to file" || line["caller"] == nil {
+		t.Errorf("expected json line with caller in the file, actual %q", data)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		errMsg string
+	}{
+		{"unknown key", `{"levle": "info"}`, `unknown field "levle"`},
+		{"unknown level", `{"level": "verbose"}`, `unknown level "verbose"`},
+		{"file without path", `{"outputs": [{"type": "stdout"}, {"type": "file"}]}`, `outputs[1]: path is required`},
+		{"unknown hook", `{"hooks": [{"type": "kafka"}]}`, `hooks[0]: unknown type "kafka"`},
+	}
+	for _, test := range tests {
+		cfg, err := ParseConfig([]byte(test.config))
+		if err == nil {
+			err = NewLogx("test").ApplyConfig(cfg)
+		}
+		if err == nil || !strings.Contains(err.Error(), test.errMsg) {
+			t.Errorf("Test %s: expected error containing %q, actual %v", test.name, test.errMsg, err)
+		}
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logx.json")
+	writeConfig(t, path, `{"level": "info"}`)
+
+	logger := NewLogx("app")
+	loader, err := logger.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	defer loader.Close()
+	loader.Watch(10 * time.Millisecond)
+
+	writeConfig(t, path, `{"level": "debug"}`)
+	deadline := time.Now().Add(3 * time.Second)
+	for logger.GetLevel() != DebugLevel && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if logger.GetLevel() != DebugLevel {
+		t.Errorf("expected config reloaded after the file changed")
+	}
+}
+
+func TestApplyConfigWhileLogging(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath, logfmtPath := filepath.Join(dir, "json.log"), filepath.Join(dir, "logfmt.log")
+	configs := []*Config{
+		{Level: "info", ReportCaller: true, Formatter: FormatterConfig{Type: "json"},
+			Outputs: []OutputConfig{{Type: "file", Path: jsonPath}}},
+		{Level: "info", Formatter: FormatterConfig{Type: "logfmt"},
+			Outputs: []OutputConfig{{Type: "file", Path: logfmtPath}}},
+	}
+
+	logger := NewLogx("app", WithoutEnv())
+	if err := logger.ApplyConfig(configs[0]); err != nil {
+		t.Fatalf("ApplyConfig() error: %v", err)
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				logger.Info("racing")
+			}
+		}
+	}()
+	for i := 1; i <= 50; i++ {
+		if err := logger.ApplyConfig(configs[i%2]); err != nil {
+			t.Fatalf("ApplyConfig() error: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(stop)
+	<-done
+	_ = logger.Close()
+
+	// an entry is formatted and written by the same config
+	data, _ := os.ReadFile(jsonPath)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if !json.Valid([]byte(line)) {
+			t.Fatalf("expected json lines in %s, actual %q", jsonPath, line)
+		}
+	}
+	data, _ = os.ReadFile(logfmtPath)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if strings.HasPrefix(line, "{") {
+			t.Fatalf("expected logfmt lines in %s, actual %q", logfmtPath, line)
+		}
+	}
+}
+
+// closingHook counts the hooks closed, it's created by the "closing-test" factory.
+type closingHook struct {
+	captureHook
+	closed *int32
+}
+
+func (h *closingHook) Close() error {
+	atomic.AddInt32(h.closed, 1)
+	return nil
+}
+
+func TestApplyConfigClosesHooks(t *testing.T) {
+	var closed int32
+	RegisterHookFactory("closing-test", func(options json.RawMessage) (Hook, error) {
+		if string(options) == `"fail"` {
+			return nil, errors.New("broken")
+		}
+		return &closingHook{closed: &closed}, nil
+	})
+	l := NewLogx("app", WithoutEnv(), WithOutput(nil))
+	applyTo := func(config string) error {
+		cfg, _ := ParseConfig([]byte(config))
+		return l.ApplyConfig(cfg)
+	}
+	if err := applyTo(`{"hooks": [{"type": "closing-test"}, {"type": "closing-test"}]}`); err != nil {
+		t.Fatalf("ApplyConfig() error: %v", err)
+	}
+
+	// the hooks built before the failed one are closed, the running ones are kept
+	if err := applyTo(`{"hooks": [{"type": "closing-test"}, {"type": "closing-test", "options": "fail"}]}`); err == nil {
+		t.Fatalf("expected ApplyConfig() error")
+	}
+	if n := atomic.LoadInt32(&closed); n != 1 {
+		t.Errorf("expected 1 hook closed after the failed config, actual %d", n)
+	}
+
+	// the hooks of the replaced config are closed
+	if err := applyTo(`{}`); err != nil {
+		t.Fatalf("ApplyConfig() error: %v", err)
+	}
+	if n := atomic.LoadInt32(&closed); n != 3 {
+		t.Errorf("expected 3 hooks closed after reload, actual %d", n)
+	}
+}
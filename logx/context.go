@@ -0,0 +1,92 @@
+package logx
+
+import (
+	"context"
+	"sync"
+)
+
+type contextKey string
+
+// Well-known context keys, their values are lifted into fields by the default extractor, e.g.
+// context.WithValue(ctx, logx.RequestIdKey, "abc") results in the field requestId=abc.
+const (
+	RequestIdKey contextKey = "requestId"
+	TraceIdKey   contextKey = "traceId"
+	UserIdKey    contextKey = "userId"
+)
+
+type entryContextKey struct{}
+
+// ContextExtractor lifts values of the context into fields.
+type ContextExtractor func(ctx context.Context) Fields
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   = []ContextExtractor{defaultExtractor}
+)
+
+func defaultExtractor(ctx context.Context) Fields {
+	var fields Fields
+	for _, key := range []contextKey{RequestIdKey, TraceIdKey, UserIdKey} {
+		if v := ctx.Value(key); v != nil {
+			if fields == nil {
+				fields = make(Fields, 3)
+			}
+			fields[string(key)] = v
+		}
+	}
+	return fields
+}
+
+// RegisterContextExtractor adds an extractor which is called by Entry.WithContext.
+func RegisterContextExtractor(extractor ContextExtractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	extractors = append(extractors, extractor)
+}
+
+// NewContext returns a copy of ctx which carries the entry.
+func NewContext(ctx context.Context, entry *Entry) context.Context {
+	return context.WithValue(ctx, entryContextKey{}, entry)
+}
+
+// FromContext returns a copy of the entry stored by NewContext,
+// or an empty entry of the std logger if there is none.
+func FromContext(ctx context.Context) *Entry {
+	if ctx != nil {
+		if entry, ok := ctx.Value(entryContextKey{}).(*Entry); ok && entry != nil {
+			return entry.WithFields(nil)
+		}
+	}
+	return std.WithFields(nil)
+}
+
+// Ctx returns the entry of the context with the extracted fields, e.g.
+// logx.Ctx(c.Request.Context()).Info("hello")
+func Ctx(ctx context.Context) *Entry {
+	return FromContext(ctx).WithContext(ctx)
+}
+
+// WithContext returns a copy of the entry carrying ctx and the fields lifted by the extractors.
+func (e *Entry) WithContext(ctx context.Context) *Entry {
+	entry := e.WithFields(nil)
+	entry.Context = ctx
+	if ctx == nil {
+		return entry
+	}
+
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+	for _, extractor := range extractors {
+		for k, v := range extractor(ctx) {
+			entry.Fields[k] = v
+		}
+	}
+	return entry
+}
+
+func (l *LogX) WithContext(ctx context.Context) *Entry {
+	entry := l.getEntry()
+	defer l.releaseEntry(entry)
+	return entry.WithContext(ctx)
+}
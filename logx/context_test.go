@@ -0,0 +1,44 @@
+package logx
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+type tenantKey struct{}
+
+// resetContextExtractors removes the registered extractors and keeps the default one.
+func resetContextExtractors() {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	extractors = []ContextExtractor{defaultExtractor}
+}
+
+func TestContext(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := NewLogx("test", WithOutput(&buffer), WithFormatter(NewLogfmtFormatter()))
+	RegisterContextExtractor(func(ctx context.Context) Fields {
+		if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+			return Fields{"tenant": tenant}
+		}
+		return nil
+	})
+	t.Cleanup(resetContextExtractors)
+
+	ctx := context.WithValue(context.Background(), RequestIdKey, "r-1")
+	ctx = context.WithValue(ctx, tenantKey{}, "acme")
+	ctx = NewContext(ctx, logger.WithField("module", "api"))
+
+	Ctx(ctx).Info("hello")
+	for _, expected := range []string{"module=api", "requestId=r-1", "tenant=acme"} {
+		if !strings.Contains(buffer.String(), expected) {
+			t.Errorf("expected %s in %q", expected, buffer.String())
+		}
+	}
+
+	if entry := FromContext(context.Background()); entry.Logger != std {
+		t.Errorf("expected std logger when the context carries no entry")
+	}
+}
@@ -0,0 +1,111 @@
+package logx
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithDedup collapses identical consecutive entries (same level, message and fields).
+// The repeats are held back, and a summary entry with a "repeated" field is written
+// when a different entry arrives, the window expires or the logger is flushed.
+// A window <= 0 disables dedup.
+func WithDedup(window time.Duration) Option {
+	return func(l *LogX) {
+		if window <= 0 {
+			l.deduper = nil
+			return
+		}
+		l.deduper = &deduper{window: window}
+	}
+}
+
+type deduper struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	key     string
+	last    *Entry
+	repeats int
+	timer   *time.Timer
+}
+
+// hold reports whether the entry is a repeat and has been held back.
+func (d *deduper) hold(e *Entry) bool {
+	key := dedupKey(e)
+
+	d.mu.Lock()
+	if d.last != nil && d.key == key {
+		d.repeats++
+		if d.timer == nil {
+			d.timer = time.AfterFunc(d.window, d.flush)
+		}
+		d.mu.Unlock()
+		return true
+	}
+
+	summary := d.takeSummary()
+	d.key = key
+	d.last = e.clone()
+	d.mu.Unlock()
+
+	if summary != nil {
+		summary.fire()
+	}
+	return false
+}
+
+// flush writes the summary of the held repeats.
+func (d *deduper) flush() {
+	d.mu.Lock()
+	summary := d.takeSummary()
+	d.mu.Unlock()
+
+	if summary != nil {
+		summary.fire()
+	}
+}
+
+// takeSummary must be called with d.mu held.
+func (d *deduper) takeSummary() *Entry {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if d.repeats == 0 {
+		return nil
+	}
+
+	summary := d.last.clone()
+	summary.Fields["repeated"] = d.repeats
+	summary.Message = fmt.Sprintf("previous message repeated %d times", d.repeats)
+	summary.Time = time.Now()
+	d.repeats = 0
+	return summary
+}
+
+func dedupKey(e *Entry) string {
+	var b strings.Builder
+	b.WriteString(e.Logger.Name)
+	b.WriteByte(0)
+	b.WriteString(e.Level.String())
+	b.WriteByte(0)
+	b.WriteString(e.Message)
+	if len(e.Fields) > 0 {
+		b.WriteByte(0)
+		// map keys are printed in sorted order
+		_, _ = fmt.Fprint(&b, e.Fields)
+	}
+	for _, f := range e.TypedFields {
+		b.WriteByte(0)
+		b.WriteString(f.Key)
+		b.WriteByte('=')
+		b.WriteString(f.text(time.RFC3339Nano))
+	}
+	if e.Err != nil {
+		b.WriteByte(0)
+		b.WriteString(safeString(e.Err.Error))
+	}
+	return b.String()
+}
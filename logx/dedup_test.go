@@ -0,0 +1,74 @@
+package logx
+
+import (
+	"errors"
+	"github.com/xiaorui77/goutils/wait"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDedup(t *testing.T) {
+	hook := &captureHook{}
+	logger := NewLogx("test", WithOutput(nil), WithHook(hook), WithDedup(time.Hour))
+	for i := 0; i < 3; i++ {
+		logger.WithField("host", "db-1").Warn("reconnecting")
+	}
+	logger.WithField("host", "db-2").Warn("reconnecting")
+	logger.Info("connected")
+
+	expected := []string{"reconnecting", "previous message repeated 2 times", "reconnecting", "connected"}
+	if actual := hook.messages(); strings.Join(actual, "|") != strings.Join(expected, "|") {
+		t.Fatalf("expected %v, actual %v", expected, actual)
+	}
+	summary := hook.entries[1]
+	if summary.Fields["repeated"] != 2 || summary.Fields["host"] != "db-1" || summary.Level != WarnLevel {
+		t.Errorf("unexpected summary entry %+v", summary)
+	}
+}
+
+func TestDedupErrors(t *testing.T) {
+	hook := &captureHook{}
+	logger := NewLogx("test", WithOutput(nil), WithHook(hook), WithDedup(time.Hour))
+	logger.WithError(errors.New("timeout")).Error("query failed")
+	logger.WithError(errors.New("timeout")).Error("query failed")
+	logger.WithError(errors.New("connection reset")).Error("query failed")
+
+	expected := []string{"query failed", "previous message repeated 1 times", "query failed"}
+	if actual := hook.messages(); strings.Join(actual, "|") != strings.Join(expected, "|") {
+		t.Fatalf("expected %v, actual %v", expected, actual)
+	}
+	if err := hook.entries[2].Err; err == nil || err.Error() != "connection reset" {
+		t.Errorf("expected the second error kept, actual %v", err)
+	}
+}
+
+func TestDedupWindow(t *testing.T) {
+	hook := &captureHook{}
+	logger := NewLogx("test", WithOutput(nil), WithHook(hook), WithDedup(10*time.Millisecond))
+	for i := 0; i < 3; i++ {
+		logger.Error("timeout")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wait.WaitUntil(func() bool { return len(hook.messages()) == 2 })
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("expected summary after the window expired, actual %v", hook.messages())
+	}
+}
+
+func TestDedupDisabled(t *testing.T) {
+	hook := &captureHook{}
+	logger := NewLogx("test", WithOutput(nil), WithHook(hook), WithDedup(0))
+	for i := 0; i < 3; i++ {
+		logger.Warn("reconnecting")
+	}
+	if actual := hook.messages(); len(actual) != 3 {
+		t.Errorf("expected every entry written with a zero window, actual %v", actual)
+	}
+}
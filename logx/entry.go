@@ -2,12 +2,14 @@ package logx
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"reflect"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -23,13 +25,33 @@ type Entry struct {
 
 	Fields Fields
 
+	// TypedFields are set by the Logw family functions, they are encoded after Fields.
+	TypedFields []Field
+
 	Level Level
 
 	Message string
 
 	Caller *runtime.Frame
 
+	// Stack is captured at or above the stacktrace level of the logger, see WithStacktrace.
+	Stack []runtime.Frame
+
+	// Err is set by WithError.
+	Err error
+
 	Buffer *bytes.Buffer
+
+	// Context is set by WithContext, it is available to hooks and formatters.
+	Context context.Context
+
+	// pc is the caller given by an adapter, e.g. slog.Record.PC, it is used instead of GetCaller.
+	pc uintptr
+
+	// settings of the logger are loaded once per entry, see loadSettings
+	settings       settings
+	settingsLoaded bool
+	generation     uint64
 }
 
 func NewEntry(l *LogX) *Entry {
@@ -55,23 +77,67 @@ func (e *Entry) Log(calldepath int, level Level, msg string) {
 
 	e.Level = level
 	e.Message = msg
+	e.settingsLoaded = false
 
 	if e.Time.IsZero() {
 		e.Time = time.Now()
 	}
 
-	if e.Logger.ReportCaller {
-		e.Caller = GetCaller(calldepath + 1)
+	if e.loadSettings().reportCaller {
+		if e.pc != 0 {
+			frame, _ := runtime.CallersFrames([]uintptr{e.pc}).Next()
+			e.Caller = &frame
+		} else {
+			e.Caller = GetCaller(calldepath + 1)
+		}
+	}
+	e.Stack = nil
+
+	if s := e.Logger.sampler; s != nil && !s.allow(e) {
+		return
+	}
+
+	if d := e.Logger.deduper; d != nil && d.hold(e) {
+		return
 	}
 
-	// fire hooks
-	_ = e.Logger.fireHooks(e.Level, e)
+	// captured after sampling and dedup, the suppressed entries don't pay for it
+	if lvl, ok := e.Logger.stacktraceLevel(); ok && level <= lvl {
+		e.Stack = GetStack(calldepath + 1)
+	}
 
-	if e.Logger.Out != nil {
+	e.fire()
+}
+
+// fire passes the entry to hooks and writes it to the output.
+func (e *Entry) fire() {
+	e.Logger.fireHooks(e.Level, e)
+
+	if sinks := e.Logger.getSinks(); len(sinks) > 0 {
+		e.writeSinks(sinks)
+	} else if e.loadSettings().out != nil {
 		e.write()
 	}
 }
 
+// loadSettings returns the settings of the logger loaded by the first call for the entry,
+// so a concurrent SetFormatter or ApplyConfig doesn't mix two configurations in one entry.
+func (e *Entry) loadSettings() *settings {
+	if !e.settingsLoaded {
+		// the generation is loaded first, a change after it is detected by settingsChanged
+		e.generation = atomic.LoadUint64(e.Logger.generation)
+		e.settings = e.Logger.effectiveSettings()
+		e.settingsLoaded = true
+	}
+	return &e.settings
+}
+
+// settingsChanged tells whether the settings were replaced after the entry loaded them,
+// the caller holds Logger.mu which guards the changes.
+func (e *Entry) settingsChanged() bool {
+	return atomic.LoadUint64(e.Logger.generation) != e.generation
+}
+
 func (e *Entry) write() {
 	buffer := bufferPool.Get().(*bytes.Buffer)
 	defer func() {
@@ -79,20 +145,43 @@ func (e *Entry) write() {
 		buffer.Reset()
 		bufferPool.Put(buffer)
 	}()
-	buffer.Reset()
-	e.Buffer = buffer
 
-	format, err := e.Logger.Formatter.Format(e)
-	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "Failed to format logger, %v\n", err)
+	for {
+		buffer.Reset()
+		e.Buffer = buffer
+		format, err := e.loadSettings().formatter.Format(e)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Failed to format logger, %v\n", err)
+			return
+		}
+
+		e.Logger.mu.Lock()
+		if e.settingsChanged() {
+			// reconfigured meanwhile, the replaced output may be closed already, format by the current settings
+			e.Logger.mu.Unlock()
+			e.settingsLoaded = false
+			continue
+		}
+		if out := e.settings.out; out != nil {
+			if _, err := out.Write(format); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "Failed to write to Output, %v\n", err)
+			}
+		}
+		e.Logger.mu.Unlock()
 		return
 	}
+}
 
-	e.Logger.mu.Lock()
-	defer e.Logger.mu.Unlock()
-	if _, err := e.Logger.Out.Write(format); err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "Failed to write to Output, %v\n", err)
+// clone returns a copy which can be kept after the entry is released.
+func (e *Entry) clone() *Entry {
+	c := *e
+	c.Buffer = nil
+	c.Fields = make(Fields, len(e.Fields)+1)
+	for k, v := range e.Fields {
+		c.Fields[k] = v
 	}
+	c.TypedFields = append([]Field(nil), e.TypedFields...)
+	return &c
 }
 
 func (e *Entry) WithFields(fields Fields) *Entry {
@@ -101,14 +190,18 @@ func (e *Entry) WithFields(fields Fields) *Entry {
 		data[k] = v
 	}
 	for k, v := range fields {
+		// skip functions and pointers to functions, they can't be formatted
 		if t := reflect.TypeOf(v); t != nil &&
-			t.Kind() != reflect.Func && t.Kind() != reflect.Ptr && t.Elem().Kind() != reflect.Func {
-			data[k] = v
+			(t.Kind() == reflect.Func || t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Func) {
+			continue
 		}
+		data[k] = v
 	}
 	return &Entry{
-		Logger: e.Logger,
-		Fields: data,
+		Logger:  e.Logger,
+		Fields:  data,
+		Err:     e.Err,
+		Context: e.Context,
 	}
 }
 
@@ -116,8 +209,31 @@ func (e *Entry) WithField(k string, v interface{}) *Entry {
 	return e.WithFields(Fields{k: v})
 }
 
+// AllFields returns Fields merged with TypedFields, typed fields are boxed.
+// The message of Err is added as "error" unless a field has the key.
+func (e *Entry) AllFields() Fields {
+	if len(e.TypedFields) == 0 && e.Err == nil {
+		return e.Fields
+	}
+	data := make(Fields, len(e.Fields)+len(e.TypedFields)+1)
+	if e.Err != nil {
+		data["error"] = safeString(e.Err.Error)
+	}
+	for k, v := range e.Fields {
+		data[k] = v
+	}
+	for _, f := range e.TypedFields {
+		data[f.Key] = f.Value()
+	}
+	return data
+}
+
 // Print functions
 
+func (e *Entry) Trace(args ...interface{}) {
+	e.Log(2, TraceLevel, fmt.Sprint(args...))
+}
+
 func (e *Entry) Debug(args ...interface{}) {
 	e.Log(2, DebugLevel, fmt.Sprint(fmt.Sprint(args...)))
 }
@@ -134,6 +250,7 @@ func (e *Entry) Error(args ...interface{}) {
 	e.Log(2, ErrorLevel, fmt.Sprint(args...))
 }
 
+// Fatal logs at FatalLevel, unlike LogX.Fatal it doesn't exit.
 func (e *Entry) Fatal(args ...interface{}) {
 	e.Log(2, FatalLevel, fmt.Sprint(args...))
 }
@@ -144,6 +261,10 @@ func (e *Entry) Panic(args ...interface{}) {
 
 // Printf family functions
 
+func (e *Entry) Tracef(format string, args ...interface{}) {
+	e.Log(2, TraceLevel, fmt.Sprintf(format, args...))
+}
+
 func (e *Entry) Debugf(format string, args ...interface{}) {
 	e.Log(2, DebugLevel, fmt.Sprintf(format, fmt.Sprint(args...)))
 }
@@ -168,6 +289,42 @@ func (e *Entry) Panicf(format string, args ...interface{}) {
 	e.Log(2, PanicLevel, fmt.Sprintf(format, fmt.Sprint(args...)))
 }
 
+// Printw family functions
+
+func (e *Entry) Logw(calldepath int, level Level, msg string, fields ...Field) {
+	e.TypedFields = fields
+	e.Log(calldepath+1, level, msg)
+	e.TypedFields = nil
+}
+
+func (e *Entry) Tracew(msg string, fields ...Field) {
+	e.Logw(2, TraceLevel, msg, fields...)
+}
+
+func (e *Entry) Debugw(msg string, fields ...Field) {
+	e.Logw(2, DebugLevel, msg, fields...)
+}
+
+func (e *Entry) Infow(msg string, fields ...Field) {
+	e.Logw(2, InfoLevel, msg, fields...)
+}
+
+func (e *Entry) Warnw(msg string, fields ...Field) {
+	e.Logw(2, WarnLevel, msg, fields...)
+}
+
+func (e *Entry) Errorw(msg string, fields ...Field) {
+	e.Logw(2, ErrorLevel, msg, fields...)
+}
+
+func (e *Entry) Fatalw(msg string, fields ...Field) {
+	e.Logw(2, FatalLevel, msg, fields...)
+}
+
+func (e *Entry) Panicw(msg string, fields ...Field) {
+	e.Logw(2, PanicLevel, msg, fields...)
+}
+
 // utils functions
 
 func GetCaller(skip int) *runtime.Frame {
@@ -179,8 +336,8 @@ func GetCaller(skip int) *runtime.Frame {
 	for f, again := frames.Next(); again; f, again = frames.Next() {
 		pkg := getPackageName(f.Function)
 
-		// If the caller isn't part of this package, we're done
-		if pkg != packageName {
+		// If the caller isn't part of this package or the log packages redirected to it, we're done
+		if pkg != packageName && pkg != "log" && pkg != "log/slog" {
 			return &f //nolint:scopelint
 		}
 	}
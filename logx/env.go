@@ -0,0 +1,143 @@
+package logx
+
+import (
+	"fmt"
+	"github.com/xiaorui77/goutils/logx/rotate"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment variables read by WithEnv.
+const (
+	// EnvLevel is the level and optional module levels, e.g. "info" or "info,app.db.*=debug".
+	EnvLevel = "LOGX_LEVEL"
+	// EnvFormat is one of text, json and logfmt.
+	EnvFormat = "LOGX_FORMAT"
+	// EnvOutput is stdout, stderr or a file path. An output wrapped by WithAsync is replaced by
+	// an AsyncWriter of the same size and policy wrapping the new output.
+	EnvOutput = "LOGX_OUTPUT"
+	// EnvColor turns the color of the text formatter on or off, it's ignored with a warning for other formatters.
+	EnvColor = "LOGX_COLOR"
+	// EnvCaller turns caller reporting on or off.
+	EnvCaller = "LOGX_CALLER"
+)
+
+// WithEnv configures the logger by the LOGX_* environment variables, which override the options before it,
+// see the variables for the exceptions. Invalid values are reported to stderr and ignored.
+// Init applies it by default, see WithoutEnv.
+func WithEnv() Option {
+	return func(l *LogX) {
+		if !l.noEnv {
+			l.applyEnv()
+		}
+	}
+}
+
+// WithoutEnv stops WithEnv and Init from reading the environment variables.
+func WithoutEnv() Option {
+	return func(l *LogX) {
+		l.noEnv = true
+	}
+}
+
+func (l *LogX) applyEnv() {
+	if v, ok := os.LookupEnv(EnvLevel); ok && v != "" {
+		l.applyEnvLevel(v)
+	}
+
+	if v, ok := os.LookupEnv(EnvFormat); ok && v != "" {
+		switch strings.ToLower(v) {
+		case "text":
+			l.SetFormatter(NewTextFormatter(l, true))
+		case "json":
+			l.SetFormatter(NewJSONFormatter())
+		case "logfmt":
+			l.SetFormatter(NewLogfmtFormatter())
+		default:
+			envWarning(EnvFormat, v, "expected text, json or logfmt")
+		}
+	}
+
+	if v, ok := os.LookupEnv(EnvColor); ok && v != "" {
+		colorful, err := strconv.ParseBool(v)
+		if err != nil {
+			envWarning(EnvColor, v, "expected a boolean")
+		} else if _, ok := l.loadSettings().formatter.(*TextFormatter); ok {
+			l.SetFormatter(NewTextFormatter(l, colorful))
+		} else {
+			envWarning(EnvColor, v, "only the text formatter has colors")
+		}
+	}
+
+	if v, ok := os.LookupEnv(EnvOutput); ok && v != "" {
+		var out io.Writer
+		switch strings.ToLower(v) {
+		case "stdout":
+			out = os.Stdout
+		case "stderr":
+			out = os.Stderr
+		default:
+			w, err := rotate.New(v)
+			if err != nil {
+				envWarning(EnvOutput, v, err.Error())
+			} else {
+				out = w
+			}
+		}
+		if out != nil {
+			l.setEnvOutput(out)
+		}
+	}
+
+	if v, ok := os.LookupEnv(EnvCaller); ok && v != "" {
+		reportCaller, err := strconv.ParseBool(v)
+		if err != nil {
+			envWarning(EnvCaller, v, "expected a boolean")
+		} else {
+			l.SetReportCaller(reportCaller)
+		}
+	}
+}
+
+// setEnvOutput replaces the output, keeping the AsyncWriter set by WithAsync around the new output.
+// The replaced output is left open, it was given by the caller.
+func (l *LogX) setEnvOutput(out io.Writer) {
+	async, ok := l.loadSettings().out.(*AsyncWriter)
+	if !ok {
+		l.SetOutput(out)
+		return
+	}
+	l.SetOutput(NewAsyncWriter(out, len(async.ring), async.policy))
+	async.stop()
+}
+
+// applyEnvLevel parses "info,app.db.*=debug", invalid items are skipped.
+func (l *LogX) applyEnvLevel(v string) {
+	for _, item := range strings.Split(v, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		pattern, name := "", item
+		if i := strings.IndexByte(item, '='); i >= 0 {
+			pattern, name = strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])
+		}
+
+		level, ok := LookupLevel(name)
+		if !ok {
+			envWarning(EnvLevel, item, "unknown level")
+			continue
+		}
+		if pattern == "" {
+			l.SetLevel(level)
+		} else if err := l.SetModuleLevel(pattern, level); err != nil {
+			envWarning(EnvLevel, item, err.Error())
+		}
+	}
+}
+
+func envWarning(key, value, reason string) {
+	_, _ = fmt.Fprintf(os.Stderr, "logx: ignored invalid %s=%q, %s\n", key, value, reason)
+}
@@ -0,0 +1,76 @@
+package logx
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	t.Setenv(EnvLevel, "debug,app.db.*=error")
+	t.Setenv(EnvFormat, "json")
+	t.Setenv(EnvOutput, path)
+	t.Setenv(EnvCaller, "true")
+
+	logger := NewLogx("app", WithLevel(WarnLevel), WithEnv())
+	defer logger.Close()
+	if logger.GetLevel() != DebugLevel || logger.Named("db").Named("pool").GetLevel() != ErrorLevel {
+		t.Errorf("expected levels of %s", EnvLevel)
+	}
+	if _, ok := logger.GetFormatter().(*JSONFormatter); !ok {
+		t.Errorf("expected json formatter, actual %T", logger.GetFormatter())
+	}
+	if !logger.GetReportCaller() {
+		t.Errorf("expected caller reporting on")
+	}
+	logger.Debug("to file")
+	if data, _ := os.ReadFile(path); len(data) == 0 {
+		t.Errorf("expected output to %s", path)
+	}
+}
+
+func TestWithEnvInvalid(t *testing.T) {
+	t.Setenv(EnvLevel, "verbose")
+	t.Setenv(EnvFormat, "xml")
+	t.Setenv(EnvCaller, "maybe")
+
+	logger := NewLogx("app", WithLevel(WarnLevel), WithEnv())
+	if logger.GetLevel() != WarnLevel || logger.GetReportCaller() {
+		t.Errorf("expected invalid values ignored")
+	}
+	if _, ok := logger.GetFormatter().(*TextFormatter); !ok {
+		t.Errorf("expected text formatter kept, actual %T", logger.GetFormatter())
+	}
+
+	t.Setenv(EnvLevel, "debug")
+	logger = NewLogx("app", WithoutEnv(), WithLevel(WarnLevel), WithEnv())
+	if logger.GetLevel() != WarnLevel {
+		t.Errorf("expected environment ignored by WithoutEnv")
+	}
+}
+
+func TestWithEnvAsyncOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	t.Setenv(EnvOutput, path)
+	t.Setenv(EnvColor, "false")
+
+	var buffer bytes.Buffer
+	logger := NewLogx("app", WithOutput(&buffer), WithAsync(16, OverflowDropNewest),
+		WithFormatter(NewJSONFormatter()), WithEnv())
+	async, ok := logger.GetOutput().(*AsyncWriter)
+	if !ok || async.policy != OverflowDropNewest || len(async.ring) != 16 {
+		t.Fatalf("expected output wrapped by the AsyncWriter, actual %T", logger.GetOutput())
+	}
+	if _, ok := logger.GetFormatter().(*JSONFormatter); !ok {
+		t.Errorf("expected json formatter kept, actual %T", logger.GetFormatter())
+	}
+	logger.Info("to file")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Contains(data, []byte("to file")) || buffer.Len() != 0 {
+		t.Errorf("expected output to %s only, actual %q", path, data)
+	}
+}
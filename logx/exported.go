@@ -1,13 +1,16 @@
 package logx
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"os"
+	"sync/atomic"
 )
 
 func SetName(name string) {
 	std.Name = name
+	// the module level is resolved by name
+	atomic.StoreUint64(&std.moduleCache, 0)
 }
 
 func SetInstance(instance string) {
@@ -22,26 +25,92 @@ func SetLevelS(level string) {
 	std.SetLevel(ParseLevel(level))
 }
 
+func GetLevel() Level {
+	return std.GetLevel()
+}
+
+// SetModuleLevel overrides the level of the loggers whose name matches the pattern, e.g. "app.db.*".
+func SetModuleLevel(pattern string, level Level) error {
+	return std.SetModuleLevel(pattern, level)
+}
+
+func RemoveModuleLevel(pattern string) {
+	std.RemoveModuleLevel(pattern)
+}
+
 func SetReportCaller(reportCaller bool) {
 	std.SetReportCaller(reportCaller)
 }
 
+// Std returns the std logger, it is replaced by Init.
+func Std() *LogX {
+	return std
+}
+
+func SetExitFunc(exit func(code int)) {
+	std.SetExitFunc(exit)
+}
+
+func SetStacktraceLevel(level Level) {
+	std.SetStacktraceLevel(level)
+}
+
 func SetOutput(output io.Writer) {
 	std.SetOutput(output)
 }
 
-func AddHook(hook Hook) {
-	std.AddHook(hook)
+func SetFormatter(formatter Formatter) {
+	std.SetFormatter(formatter)
+}
+
+func AddHook(hook Hook, opts ...HookOption) {
+	std.AddHook(hook, opts...)
+}
+
+func RemoveHook(hook Hook) {
+	std.RemoveHook(hook)
+}
+
+func SetHookErrorHandler(handler HookErrorHandler) {
+	std.SetHookErrorHandler(handler)
+}
+
+// Flush writes the buffered entries of the std logger.
+func Flush() error {
+	return std.Flush()
+}
+
+// Close flushes and closes the output of the std logger, should be called on shutdown.
+func Close() error {
+	return std.Close()
+}
+
+// Named returns a child logger of the std logger.
+func Named(name string) *LogX {
+	return std.Named(name)
+}
+
+// With returns a child logger of the std logger which adds the fields to every entry.
+func With(fields Fields) *LogX {
+	return std.With(fields)
 }
 
 func WithFields(fields Fields) *Entry {
 	return std.WithFields(fields)
 }
 
+func WithContext(ctx context.Context) *Entry {
+	return std.WithContext(ctx)
+}
+
 func WithField(key string, value interface{}) *Entry {
 	return std.WithField(key, value)
 }
 
+func WithError(err error) *Entry {
+	return std.WithError(err)
+}
+
 func F(key string, value interface{}) *Entry {
 	return std.WithField(key, value)
 }
@@ -54,6 +123,7 @@ func Log(level Level, args ...interface{}) {
 	std.Log(3, level, args...)
 }
 
+func Trace(args ...interface{}) { Log(TraceLevel, args...) }
 func Debug(args ...interface{}) { Log(DebugLevel, args...) }
 
 func Info(args ...interface{}) { Log(InfoLevel, args...) }
@@ -64,7 +134,7 @@ func Error(args ...interface{}) { Log(ErrorLevel, args...) }
 
 func Fatal(args ...interface{}) {
 	Log(FatalLevel, args...)
-	os.Exit(1)
+	std.Exit(1)
 }
 
 func Panic(args ...interface{}) {
@@ -78,6 +148,7 @@ func Logf(level Level, format string, args ...interface{}) {
 	std.Logf(3, level, format, args...)
 }
 
+func Tracef(format string, args ...interface{}) { Logf(TraceLevel, format, args...) }
 func Debugf(format string, args ...interface{}) { Logf(DebugLevel, format, args...) }
 
 func Infof(format string, args ...interface{}) { Logf(InfoLevel, format, args...) }
@@ -88,10 +159,35 @@ func Errorf(format string, args ...interface{}) { Logf(ErrorLevel, format, args.
 
 func Fatalf(format string, args ...interface{}) {
 	Logf(FatalLevel, format, args...)
-	os.Exit(1)
+	std.Exit(1)
 }
 
 func Panicf(format string, args ...interface{}) {
 	Logf(PanicLevel, format, args...)
 	panic(fmt.Sprintf(format, args...))
 }
+
+// Printw family functions
+
+func Logw(level Level, msg string, fields ...Field) {
+	std.Logw(3, level, msg, fields...)
+}
+
+func Tracew(msg string, fields ...Field) { Logw(TraceLevel, msg, fields...) }
+func Debugw(msg string, fields ...Field) { Logw(DebugLevel, msg, fields...) }
+
+func Infow(msg string, fields ...Field) { Logw(InfoLevel, msg, fields...) }
+
+func Warnw(msg string, fields ...Field) { Logw(WarnLevel, msg, fields...) }
+
+func Errorw(msg string, fields ...Field) { Logw(ErrorLevel, msg, fields...) }
+
+func Fatalw(msg string, fields ...Field) {
+	Logw(FatalLevel, msg, fields...)
+	std.Exit(1)
+}
+
+func Panicw(msg string, fields ...Field) {
+	Logw(PanicLevel, msg, fields...)
+	panic(msg)
+}
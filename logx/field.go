@@ -0,0 +1,231 @@
+package logx
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// FieldType tells how the value of Field is stored.
+type FieldType uint8
+
+const (
+	UnknownType FieldType = iota
+	StringType
+	IntType
+	UintType
+	FloatType
+	BoolType
+	DurationType
+	TimeType
+	ErrorType
+	StringerType
+	AnyType
+)
+
+// Field is a typed key-value pair, primitive values are stored without boxing, e.g.
+// logger.Infow("request done", logx.String("path", path), logx.Duration("cost", cost))
+type Field struct {
+	Key       string
+	Type      FieldType
+	Integer   int64
+	String    string
+	Interface interface{}
+}
+
+func String(key, value string) Field {
+	return Field{Key: key, Type: StringType, String: value}
+}
+
+func Int(key string, value int) Field {
+	return Field{Key: key, Type: IntType, Integer: int64(value)}
+}
+
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Type: IntType, Integer: value}
+}
+
+func Uint(key string, value uint) Field {
+	return Field{Key: key, Type: UintType, Integer: int64(value)}
+}
+
+func Uint64(key string, value uint64) Field {
+	return Field{Key: key, Type: UintType, Integer: int64(value)}
+}
+
+func Float64(key string, value float64) Field {
+	return Field{Key: key, Type: FloatType, Integer: int64(math.Float64bits(value))}
+}
+
+func Bool(key string, value bool) Field {
+	var i int64
+	if value {
+		i = 1
+	}
+	return Field{Key: key, Type: BoolType, Integer: i}
+}
+
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Type: DurationType, Integer: int64(value)}
+}
+
+// Time stores the time as unix nanoseconds, times out of the int64 range are stored boxed.
+func Time(key string, value time.Time) Field {
+	if value.Year() < 1678 || value.Year() > 2261 {
+		return Field{Key: key, Type: AnyType, Interface: value}
+	}
+	return Field{Key: key, Type: TimeType, Integer: value.UnixNano(), Interface: value.Location()}
+}
+
+// Err is a field with the key "error".
+func Err(err error) Field {
+	return NamedErr("error", err)
+}
+
+func NamedErr(key string, err error) Field {
+	return Field{Key: key, Type: ErrorType, Interface: err}
+}
+
+func Stringer(key string, value fmt.Stringer) Field {
+	return Field{Key: key, Type: StringerType, Interface: value}
+}
+
+// Any picks the typed constructor of value, falls back to storing it boxed.
+func Any(key string, value interface{}) Field {
+	switch val := value.(type) {
+	case string:
+		return String(key, val)
+	case int:
+		return Int(key, val)
+	case int64:
+		return Int64(key, val)
+	case int32:
+		return Int64(key, int64(val))
+	case uint:
+		return Uint(key, val)
+	case uint64:
+		return Uint64(key, val)
+	case uint32:
+		return Uint64(key, uint64(val))
+	case float64:
+		return Float64(key, val)
+	case float32:
+		return Float64(key, float64(val))
+	case bool:
+		return Bool(key, val)
+	case time.Duration:
+		return Duration(key, val)
+	case time.Time:
+		return Time(key, val)
+	case error:
+		return NamedErr(key, val)
+	case fmt.Stringer:
+		return Stringer(key, val)
+	}
+	return Field{Key: key, Type: AnyType, Interface: value}
+}
+
+// Value returns the boxed value, it is used when a map of fields is needed, e.g. by hooks.
+func (f Field) Value() interface{} {
+	switch f.Type {
+	case StringType:
+		return f.String
+	case IntType:
+		return f.Integer
+	case UintType:
+		return uint64(f.Integer)
+	case FloatType:
+		return math.Float64frombits(uint64(f.Integer))
+	case BoolType:
+		return f.Integer == 1
+	case DurationType:
+		return time.Duration(f.Integer)
+	case TimeType:
+		return f.time()
+	}
+	return f.Interface
+}
+
+func (f Field) time() time.Time {
+	t := time.Unix(0, f.Integer)
+	if loc, ok := f.Interface.(*time.Location); ok && loc != nil {
+		t = t.In(loc)
+	}
+	return t
+}
+
+func (f Field) writeJSON(enc *jsonEncoder) {
+	buffer := enc.buffer
+	var scratch [32]byte
+	switch f.Type {
+	case StringType:
+		writeJSONString(buffer, f.String)
+	case IntType:
+		buffer.Write(strconv.AppendInt(scratch[:0], f.Integer, 10))
+	case UintType:
+		buffer.Write(strconv.AppendUint(scratch[:0], uint64(f.Integer), 10))
+	case FloatType:
+		writeJSONFloat(buffer, math.Float64frombits(uint64(f.Integer)), 64)
+	case BoolType:
+		buffer.Write(strconv.AppendBool(scratch[:0], f.Integer == 1))
+	case DurationType:
+		writeJSONString(buffer, time.Duration(f.Integer).String())
+	case TimeType:
+		writeJSONString(buffer, f.time().Format(enc.timeFormat))
+	case ErrorType, StringerType:
+		if f.Interface == nil {
+			buffer.WriteString("null")
+			return
+		}
+		writeJSONString(buffer, f.text(enc.timeFormat))
+	default:
+		enc.writeValue(f.Interface)
+	}
+}
+
+func (f Field) writeLogfmt(buffer *bytes.Buffer, layout string) {
+	var scratch [32]byte
+	switch f.Type {
+	case IntType:
+		buffer.Write(strconv.AppendInt(scratch[:0], f.Integer, 10))
+	case UintType:
+		buffer.Write(strconv.AppendUint(scratch[:0], uint64(f.Integer), 10))
+	case BoolType:
+		buffer.Write(strconv.AppendBool(scratch[:0], f.Integer == 1))
+	default:
+		writeLogfmtString(buffer, f.text(layout))
+	}
+}
+
+// text returns the value as plain text.
+func (f Field) text(layout string) string {
+	switch f.Type {
+	case StringType:
+		return f.String
+	case IntType:
+		return strconv.FormatInt(f.Integer, 10)
+	case UintType:
+		return strconv.FormatUint(uint64(f.Integer), 10)
+	case FloatType:
+		return strconv.FormatFloat(math.Float64frombits(uint64(f.Integer)), 'g', -1, 64)
+	case BoolType:
+		return strconv.FormatBool(f.Integer == 1)
+	case DurationType:
+		return time.Duration(f.Integer).String()
+	case TimeType:
+		return f.time().Format(layout)
+	case ErrorType:
+		if err, ok := f.Interface.(error); ok {
+			return safeString(err.Error)
+		}
+		return "<nil>"
+	case StringerType:
+		if s, ok := f.Interface.(fmt.Stringer); ok {
+			return safeString(s.String)
+		}
+		return "<nil>"
+	}
+	return logfmtString(f.Interface, layout)
+}
@@ -48,7 +48,33 @@ func (f *TextFormatter) Format(entry *Entry) ([]byte, error) {
 	buffer.WriteString(" - ")
 	buffer.WriteString(entry.Message)
 
-	if f.logger.ReportCaller && entry.Caller != nil {
+	// every key is written once, typed fields override map fields and the earlier typed fields, see Entry.AllFields
+	for _, k := range sortedKeys(entry.Fields) {
+		if hasTypedField(entry.TypedFields, k) {
+			continue
+		}
+		buffer.WriteString(" ")
+		buffer.WriteString(coloring.Coloring(k, cyan, f.colorful))
+		buffer.WriteString("=")
+		writeLogfmtValue(buffer, entry.Fields[k], time.RFC3339Milli)
+	}
+	for i, field := range entry.TypedFields {
+		if hasTypedField(entry.TypedFields[i+1:], field.Key) {
+			continue
+		}
+		buffer.WriteString(" ")
+		buffer.WriteString(coloring.Coloring(field.Key, cyan, f.colorful))
+		buffer.WriteString("=")
+		field.writeLogfmt(buffer, time.RFC3339Milli)
+	}
+	if entry.Err != nil {
+		buffer.WriteString(" ")
+		buffer.WriteString(coloring.Coloring("error", cyan, f.colorful))
+		buffer.WriteString("=")
+		writeLogfmtValue(buffer, entry.Err, time.RFC3339Milli)
+	}
+
+	if entry.Caller != nil {
 		caller := buildCaller(entry)
 		if caller != "" {
 			buffer.WriteString(" - ")
@@ -56,41 +82,29 @@ func (f *TextFormatter) Format(entry *Entry) ([]byte, error) {
 		}
 	}
 
-	// 2022-02-20 03:27:20 INFO main.go:28 - log info output
+	// 2022-02-20 03:27:20  INFO - log info output key=value - main.go:28
 	buffer.WriteString("\n")
+	writeStackText(buffer, entry)
 	return buffer.Bytes(), nil
 }
 
 func levelColor(level Level) int {
-	switch level {
-	case InfoLevel:
-		return green
-	case WarnLevel:
-		return yellow
-	case ErrorLevel, FatalLevel, PanicLevel:
-		return red
-	case DebugLevel:
-		return gray
+	if desc, ok := loadLevels().levels[level]; ok {
+		return desc.color
 	}
 	return green
 }
 
+// levelString returns the short form of the level padded to 5 characters.
 func levelString(level Level) string {
-	switch level {
-	case DebugLevel:
-		return "DEBGU"
-	case InfoLevel:
-		return " INFO"
-	case WarnLevel:
-		return " WARN"
-	case ErrorLevel:
-		return "ERROR"
-	case FatalLevel:
-		return "FATAL"
-	case PanicLevel:
-		return "PANIC"
+	desc, ok := loadLevels().levels[level]
+	if !ok {
+		return "UNKNOWN"
+	}
+	if n := len(desc.short); n < 5 {
+		return "     "[n:] + desc.short
 	}
-	return "UNKNOWN"
+	return desc.short
 }
 
 func buildCaller(entry *Entry) string {
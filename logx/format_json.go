@@ -0,0 +1,346 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/xiaorui77/goutils/time"
+	"math"
+	"sort"
+	"strconv"
+	stdtime "time"
+	"unicode/utf8"
+)
+
+// JSONFormatter formats an entry as one json object per line.
+// Set a key to "" to omit the attribute.
+type JSONFormatter struct {
+	TimeKey     string
+	LevelKey    string
+	MessageKey  string
+	CallerKey   string
+	NameKey     string
+	InstanceKey string
+
+	// ErrorKey is the object of Entry.Err: {"msg":"...","chain":["..."],"stack":["function file:line"]}.
+	// A flattened field with the same key overrides it, as in Entry.AllFields.
+	ErrorKey string
+	// StacktraceKey is the array of Entry.Stack, each frame is written as "function file:line".
+	StacktraceKey string
+
+	// TimestampFormat is the layout of Entry.Time, see goutils/time.
+	TimestampFormat string
+
+	// FieldsKey nests all fields under this key, fields are flattened into the top-level object when empty.
+	FieldsKey string
+}
+
+func NewJSONFormatter() *JSONFormatter {
+	return &JSONFormatter{
+		TimeKey:         "time",
+		LevelKey:        "level",
+		MessageKey:      "msg",
+		CallerKey:       "caller",
+		NameKey:         "name",
+		InstanceKey:     "instance",
+		ErrorKey:        "error",
+		StacktraceKey:   "stacktrace",
+		TimestampFormat: time.RFC3339Milli,
+	}
+}
+
+func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
+	var buffer *bytes.Buffer
+	if entry.Buffer != nil {
+		buffer = entry.Buffer
+	} else {
+		buffer = &bytes.Buffer{}
+	}
+
+	enc := jsonEncoder{buffer: buffer, timeFormat: f.timestampFormat()}
+	buffer.WriteByte('{')
+	if f.TimeKey != "" {
+		enc.writeKey(f.TimeKey)
+		writeJSONString(buffer, entry.Time.Format(enc.timeFormat))
+	}
+	if f.LevelKey != "" {
+		enc.writeKey(f.LevelKey)
+		writeJSONString(buffer, entry.Level.String())
+	}
+	if entry.Logger != nil {
+		if f.NameKey != "" {
+			enc.writeKey(f.NameKey)
+			writeJSONString(buffer, entry.Logger.Name)
+		}
+		if f.InstanceKey != "" {
+			enc.writeKey(f.InstanceKey)
+			writeJSONString(buffer, entry.Logger.Instance)
+		}
+	}
+	if f.MessageKey != "" {
+		enc.writeKey(f.MessageKey)
+		writeJSONString(buffer, entry.Message)
+	}
+	if f.CallerKey != "" && entry.Caller != nil {
+		enc.writeKey(f.CallerKey)
+		writeJSONString(buffer, buildCaller(entry))
+	}
+
+	if f.ErrorKey != "" && entry.Err != nil && !(f.FieldsKey == "" && hasField(entry, f.ErrorKey)) {
+		enc.writeKey(f.ErrorKey)
+		f.writeError(&enc, entry.Err)
+	}
+	if f.StacktraceKey != "" && len(entry.Stack) > 0 {
+		enc.writeKey(f.StacktraceKey)
+		writeJSONStrings(buffer, stackStrings(entry.Stack))
+	}
+
+	if len(entry.Fields) > 0 || len(entry.TypedFields) > 0 {
+		if f.FieldsKey != "" {
+			enc.writeKey(f.FieldsKey)
+			buffer.WriteByte('{')
+			enc.needComma = false
+			f.writeFields(&enc, entry, false)
+			buffer.WriteByte('}')
+			enc.needComma = true
+		} else {
+			f.writeFields(&enc, entry, true)
+		}
+	}
+
+	buffer.WriteString("}\n")
+	return buffer.Bytes(), nil
+}
+
+func (f *JSONFormatter) writeError(enc *jsonEncoder, err error) {
+	buffer := enc.buffer
+	chain := ErrorChain(err)
+	buffer.WriteString(`{"msg":`)
+	writeJSONString(buffer, chain[0])
+	if len(chain) > 1 {
+		buffer.WriteString(`,"chain":`)
+		writeJSONStrings(buffer, chain[1:])
+	}
+	if stack := ErrorStack(err); len(stack) > 0 {
+		buffer.WriteString(`,"stack":`)
+		writeJSONStrings(buffer, stackStrings(stack))
+	}
+	buffer.WriteByte('}')
+}
+
+// writeFields writes every key once, the precedence is the same as Entry.AllFields:
+// a typed field overrides a map field and an earlier typed field with the same key.
+func (f *JSONFormatter) writeFields(enc *jsonEncoder, entry *Entry, flatten bool) {
+	for _, k := range sortedKeys(entry.Fields) {
+		if hasTypedField(entry.TypedFields, k) {
+			continue
+		}
+		key := k
+		if flatten && f.reserved(k, entry) {
+			key = "fields." + k
+		}
+		enc.writeKey(key)
+		enc.writeValue(entry.Fields[k])
+	}
+	for i, field := range entry.TypedFields {
+		if hasTypedField(entry.TypedFields[i+1:], field.Key) {
+			continue
+		}
+		key := field.Key
+		if flatten && f.reserved(key, entry) {
+			key = "fields." + key
+		}
+		enc.writeKey(key)
+		field.writeJSON(enc)
+	}
+}
+
+// reserved reports whether the key collides with a top-level attribute.
+func (f *JSONFormatter) reserved(key string, entry *Entry) bool {
+	switch key {
+	case f.TimeKey, f.LevelKey, f.MessageKey, f.CallerKey, f.NameKey, f.InstanceKey:
+		return true
+	case f.StacktraceKey:
+		return len(entry.Stack) > 0
+	}
+	return false
+}
+
+func hasField(entry *Entry, key string) bool {
+	_, ok := entry.Fields[key]
+	return ok || hasTypedField(entry.TypedFields, key)
+}
+
+func hasTypedField(fields []Field, key string) bool {
+	for _, field := range fields {
+		if field.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *JSONFormatter) timestampFormat() string {
+	if f.TimestampFormat == "" {
+		return time.RFC3339Milli
+	}
+	return f.TimestampFormat
+}
+
+type jsonEncoder struct {
+	buffer     *bytes.Buffer
+	timeFormat string
+	needComma  bool
+}
+
+func (enc *jsonEncoder) writeKey(key string) {
+	if enc.needComma {
+		enc.buffer.WriteByte(',')
+	}
+	enc.needComma = true
+	writeJSONString(enc.buffer, key)
+	enc.buffer.WriteByte(':')
+}
+
+// writeValue serializes the value, types which can't be marshaled are written as their %+v string.
+func (enc *jsonEncoder) writeValue(v interface{}) {
+	buffer := enc.buffer
+	switch val := v.(type) {
+	case nil:
+		buffer.WriteString("null")
+	case string:
+		writeJSONString(buffer, val)
+	case bool:
+		buffer.WriteString(strconv.FormatBool(val))
+	case int:
+		buffer.WriteString(strconv.FormatInt(int64(val), 10))
+	case int8:
+		buffer.WriteString(strconv.FormatInt(int64(val), 10))
+	case int16:
+		buffer.WriteString(strconv.FormatInt(int64(val), 10))
+	case int32:
+		buffer.WriteString(strconv.FormatInt(int64(val), 10))
+	case int64:
+		buffer.WriteString(strconv.FormatInt(val, 10))
+	case uint:
+		buffer.WriteString(strconv.FormatUint(uint64(val), 10))
+	case uint8:
+		buffer.WriteString(strconv.FormatUint(uint64(val), 10))
+	case uint16:
+		buffer.WriteString(strconv.FormatUint(uint64(val), 10))
+	case uint32:
+		buffer.WriteString(strconv.FormatUint(uint64(val), 10))
+	case uint64:
+		buffer.WriteString(strconv.FormatUint(val, 10))
+	case float32:
+		writeJSONFloat(buffer, float64(val), 32)
+	case float64:
+		writeJSONFloat(buffer, val, 64)
+	case error:
+		writeJSONString(buffer, safeString(val.Error))
+	case stdtime.Time:
+		writeJSONString(buffer, val.Format(enc.timeFormat))
+	case fmt.Stringer:
+		writeJSONString(buffer, safeString(val.String))
+	default:
+		data, err := safeMarshal(val)
+		if err != nil {
+			writeJSONString(buffer, fmt.Sprintf("%+v", val))
+			return
+		}
+		buffer.Write(data)
+	}
+}
+
+func writeJSONFloat(buffer *bytes.Buffer, f float64, bits int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		writeJSONString(buffer, strconv.FormatFloat(f, 'g', -1, bits))
+		return
+	}
+	buffer.WriteString(strconv.FormatFloat(f, 'g', -1, bits))
+}
+
+const hex = "0123456789abcdef"
+
+// writeJSONString writes s as a quoted json string, html characters are not escaped.
+func writeJSONString(buffer *bytes.Buffer, s string) {
+	buffer.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+			buffer.WriteString(s[start:i])
+			switch b {
+			case '"', '\\':
+				buffer.WriteByte('\\')
+				buffer.WriteByte(b)
+			case '\n':
+				buffer.WriteString(`\n`)
+			case '\r':
+				buffer.WriteString(`\r`)
+			case '\t':
+				buffer.WriteString(`\t`)
+			default:
+				buffer.WriteString(`\u00`)
+				buffer.WriteByte(hex[b>>4])
+				buffer.WriteByte(hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			buffer.WriteString(s[start:i])
+			buffer.WriteString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buffer.WriteString(s[start:])
+	buffer.WriteByte('"')
+}
+
+func writeJSONStrings(buffer *bytes.Buffer, strs []string) {
+	buffer.WriteByte('[')
+	for i, s := range strs {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		writeJSONString(buffer, s)
+	}
+	buffer.WriteByte(']')
+}
+
+// safeString calls f and recovers from panics, e.g. a String method on a nil pointer.
+func safeString(f func() string) (s string) {
+	defer func() {
+		if r := recover(); r != nil {
+			s = fmt.Sprintf("!PANIC(%v)", r)
+		}
+	}()
+	return f()
+}
+
+func safeMarshal(v interface{}) (data []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("marshal panic: %v", r)
+		}
+	}()
+	return json.Marshal(v)
+}
+
+func sortedKeys(fields Fields) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,146 @@
+package logx
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/xiaorui77/goutils/time"
+	"strconv"
+	"strings"
+	stdtime "time"
+	"unicode/utf8"
+)
+
+// LogfmtFormatter formats an entry as logfmt key=value pairs for machine parsing:
+// time=2022-02-20T03:27:20.123+08:00 level=info name=app instance=app-0 msg="log info output" caller=main.go:28 k=v
+type LogfmtFormatter struct {
+	// TimestampFormat is the layout of Entry.Time and time fields, see goutils/time.
+	TimestampFormat string
+}
+
+func NewLogfmtFormatter() *LogfmtFormatter {
+	return &LogfmtFormatter{TimestampFormat: time.RFC3339Milli}
+}
+
+func (f *LogfmtFormatter) Format(entry *Entry) ([]byte, error) {
+	var buffer *bytes.Buffer
+	if entry.Buffer != nil {
+		buffer = entry.Buffer
+	} else {
+		buffer = &bytes.Buffer{}
+	}
+
+	layout := f.TimestampFormat
+	if layout == "" {
+		layout = time.RFC3339Milli
+	}
+
+	buffer.WriteString("time=")
+	writeLogfmtString(buffer, entry.Time.Format(layout))
+	buffer.WriteString(" level=")
+	buffer.WriteString(entry.Level.String())
+	if entry.Logger != nil {
+		buffer.WriteString(" name=")
+		writeLogfmtString(buffer, entry.Logger.Name)
+		buffer.WriteString(" instance=")
+		writeLogfmtString(buffer, entry.Logger.Instance)
+	}
+	buffer.WriteString(" msg=")
+	writeLogfmtString(buffer, entry.Message)
+	if entry.Caller != nil {
+		buffer.WriteString(" caller=")
+		writeLogfmtString(buffer, buildCaller(entry))
+	}
+
+	// every key is written once, typed fields override map fields and the earlier typed fields, see Entry.AllFields
+	for _, k := range sortedKeys(entry.Fields) {
+		if hasTypedField(entry.TypedFields, k) {
+			continue
+		}
+		buffer.WriteByte(' ')
+		writeLogfmtKey(buffer, k)
+		buffer.WriteByte('=')
+		writeLogfmtValue(buffer, entry.Fields[k], layout)
+	}
+	for i, field := range entry.TypedFields {
+		if hasTypedField(entry.TypedFields[i+1:], field.Key) {
+			continue
+		}
+		buffer.WriteByte(' ')
+		writeLogfmtKey(buffer, field.Key)
+		buffer.WriteByte('=')
+		field.writeLogfmt(buffer, layout)
+	}
+
+	if entry.Err != nil {
+		buffer.WriteString(" error=")
+		writeLogfmtValue(buffer, entry.Err, layout)
+		if chain := ErrorChain(entry.Err); len(chain) > 1 {
+			buffer.WriteString(" errorChain=")
+			writeLogfmtString(buffer, strings.Join(chain[1:], "\n"))
+		}
+		if stack := ErrorStack(entry.Err); len(stack) > 0 {
+			buffer.WriteString(" errorStack=")
+			writeLogfmtString(buffer, strings.Join(stackStrings(stack), "\n"))
+		}
+	}
+	if len(entry.Stack) > 0 {
+		buffer.WriteString(" stacktrace=")
+		writeLogfmtString(buffer, strings.Join(stackStrings(entry.Stack), "\n"))
+	}
+
+	buffer.WriteString("\n")
+	return buffer.Bytes(), nil
+}
+
+// writeLogfmtKey writes the key, characters which would break the pair are replaced with '_'.
+func writeLogfmtKey(buffer *bytes.Buffer, key string) {
+	if key == "" {
+		buffer.WriteByte('_')
+		return
+	}
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			buffer.WriteByte('_')
+		} else {
+			buffer.WriteRune(r)
+		}
+	}
+}
+
+func writeLogfmtValue(buffer *bytes.Buffer, v interface{}, layout string) {
+	writeLogfmtString(buffer, logfmtString(v, layout))
+}
+
+func logfmtString(v interface{}, layout string) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case error:
+		return safeString(val.Error)
+	case stdtime.Time:
+		return val.Format(layout)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// writeLogfmtString writes s, quoted if it contains spaces, quotes, '=' or control characters.
+func writeLogfmtString(buffer *bytes.Buffer, s string) {
+	if needsQuoting(s) {
+		buffer.WriteString(strconv.Quote(s))
+	} else {
+		buffer.WriteString(s)
+	}
+}
+
+func needsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,276 @@
+package logx
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/xiaorui77/goutils/coloring"
+	"github.com/xiaorui77/goutils/time"
+	"strconv"
+	"strings"
+)
+
+// PatternFormatter formats an entry by a layout string which is compiled once, e.g.
+// "%time{15:04:05.000} %level{pad} [%caller] %msg %fields".
+//
+// Supported tokens:
+//
+//	%time, %time{layout}                  Entry.Time, layout defaults to goutils/time.Format
+//	%level, %level{lower}, %level{pad}    level in upper case, lower case or upper case padded to 5 chars
+//	%name, %instance                      LogX.Name and LogX.Instance
+//	%caller, %file, %line, %func          caller as file:line, file, line and function name
+//	%msg                                  message
+//	%field{key}                           value of a single field
+//	%fields                               remaining fields as logfmt key=value pairs
+//	%error                                message of Entry.Err
+//	%stack                                error chain and stacks as indented lines, put it after a newline
+//	%%                                    a literal '%'
+type PatternFormatter struct {
+	layout   string
+	colorful bool
+	parts    []patternPart
+
+	// keys referenced by %field{key}, excluded from %fields
+	used map[string]struct{}
+}
+
+type patternKind int
+
+const (
+	patternLiteral patternKind = iota
+	patternTime
+	patternLevel
+	patternName
+	patternInstance
+	patternCaller
+	patternFile
+	patternLine
+	patternFunc
+	patternMessage
+	patternField
+	patternFields
+	patternError
+	patternStack
+)
+
+type patternPart struct {
+	kind patternKind
+	arg  string
+}
+
+var patternTokens = map[string]patternKind{
+	"time":     patternTime,
+	"level":    patternLevel,
+	"name":     patternName,
+	"instance": patternInstance,
+	"caller":   patternCaller,
+	"file":     patternFile,
+	"line":     patternLine,
+	"func":     patternFunc,
+	"msg":      patternMessage,
+	"field":    patternField,
+	"fields":   patternFields,
+	"error":    patternError,
+	"stack":    patternStack,
+}
+
+func NewPatternFormatter(layout string, colorful bool) (*PatternFormatter, error) {
+	f := &PatternFormatter{
+		layout:   layout,
+		colorful: colorful,
+		used:     map[string]struct{}{},
+	}
+	if err := f.compile(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *PatternFormatter) compile() error {
+	layout := f.layout
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			f.parts = append(f.parts, patternPart{kind: patternLiteral, arg: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(layout); i++ {
+		if layout[i] != '%' {
+			literal.WriteByte(layout[i])
+			continue
+		}
+		if i+1 < len(layout) && layout[i+1] == '%' {
+			literal.WriteByte('%')
+			i++
+			continue
+		}
+
+		// token name
+		start := i + 1
+		end := start
+		for end < len(layout) && isTokenChar(layout[end]) {
+			end++
+		}
+		name := layout[start:end]
+		kind, ok := patternTokens[name]
+		if !ok {
+			return fmt.Errorf("logx: unknown pattern token %q at offset %d", "%"+name, i)
+		}
+
+		// optional argument
+		var arg string
+		if end < len(layout) && layout[end] == '{' {
+			closing := strings.IndexByte(layout[end:], '}')
+			if closing < 0 {
+				return fmt.Errorf("logx: unclosed argument of %q at offset %d", "%"+name, i)
+			}
+			arg = layout[end+1 : end+closing]
+			end += closing + 1
+		}
+
+		switch kind {
+		case patternLevel:
+			if arg != "" && arg != "upper" && arg != "lower" && arg != "pad" {
+				return fmt.Errorf("logx: unknown level style %q", arg)
+			}
+		case patternField:
+			if arg == "" {
+				return fmt.Errorf("logx: %%field requires a key, e.g. %%field{requestId}")
+			}
+			f.used[arg] = struct{}{}
+		case patternTime:
+			if arg == "" {
+				arg = time.Format
+			}
+		}
+
+		flush()
+		f.parts = append(f.parts, patternPart{kind: kind, arg: arg})
+		i = end - 1
+	}
+	// %stack ends with a newline by itself
+	endsWithStack := literal.Len() == 0 && len(f.parts) > 0 && f.parts[len(f.parts)-1].kind == patternStack
+	if !strings.HasSuffix(literal.String(), "\n") && !endsWithStack {
+		literal.WriteByte('\n')
+	}
+	flush()
+	return nil
+}
+
+func isTokenChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func (f *PatternFormatter) Format(entry *Entry) ([]byte, error) {
+	var buffer *bytes.Buffer
+	if entry.Buffer != nil {
+		buffer = entry.Buffer
+	} else {
+		buffer = &bytes.Buffer{}
+	}
+
+	for _, part := range f.parts {
+		switch part.kind {
+		case patternLiteral:
+			buffer.WriteString(part.arg)
+		case patternTime:
+			buffer.WriteString(entry.Time.Format(part.arg))
+		case patternLevel:
+			buffer.WriteString(coloring.Coloring(f.levelText(entry.Level, part.arg), levelColor(entry.Level), f.colorful))
+		case patternName:
+			if entry.Logger != nil {
+				buffer.WriteString(entry.Logger.Name)
+			}
+		case patternInstance:
+			if entry.Logger != nil {
+				buffer.WriteString(entry.Logger.Instance)
+			}
+		case patternCaller:
+			if entry.Caller != nil {
+				buffer.WriteString(coloring.Coloring(buildCaller(entry), green, f.colorful))
+			}
+		case patternFile:
+			if entry.Caller != nil {
+				file := entry.Caller.File
+				if index := strings.LastIndex(file, "/"); index >= 0 {
+					file = file[index+1:]
+				}
+				buffer.WriteString(file)
+			}
+		case patternLine:
+			if entry.Caller != nil {
+				buffer.WriteString(strconv.Itoa(entry.Caller.Line))
+			}
+		case patternFunc:
+			if entry.Caller != nil {
+				function := entry.Caller.Function
+				if index := strings.LastIndex(function, "/"); index >= 0 {
+					function = function[index+1:]
+				}
+				buffer.WriteString(function)
+			}
+		case patternMessage:
+			buffer.WriteString(entry.Message)
+		case patternField:
+			if v, ok := entry.Fields[part.arg]; ok {
+				buffer.WriteString(logfmtString(v, time.RFC3339Milli))
+				break
+			}
+			for _, field := range entry.TypedFields {
+				if field.Key == part.arg {
+					buffer.WriteString(field.text(time.RFC3339Milli))
+					break
+				}
+			}
+		case patternFields:
+			f.writeFields(buffer, entry)
+		case patternError:
+			if entry.Err != nil {
+				buffer.WriteString(safeString(entry.Err.Error))
+			}
+		case patternStack:
+			writeStackText(buffer, entry)
+		}
+	}
+	return buffer.Bytes(), nil
+}
+
+func (f *PatternFormatter) levelText(level Level, style string) string {
+	switch style {
+	case "lower":
+		return level.String()
+	case "pad":
+		return levelString(level)
+	default:
+		return strings.ToUpper(level.String())
+	}
+}
+
+func (f *PatternFormatter) writeFields(buffer *bytes.Buffer, entry *Entry) {
+	first := true
+	for _, k := range sortedKeys(entry.Fields) {
+		if _, ok := f.used[k]; ok {
+			continue
+		}
+		if !first {
+			buffer.WriteByte(' ')
+		}
+		first = false
+		buffer.WriteString(coloring.Coloring(k, cyan, f.colorful))
+		buffer.WriteByte('=')
+		writeLogfmtValue(buffer, entry.Fields[k], time.RFC3339Milli)
+	}
+	for _, field := range entry.TypedFields {
+		if _, ok := f.used[field.Key]; ok {
+			continue
+		}
+		if !first {
+			buffer.WriteByte(' ')
+		}
+		first = false
+		buffer.WriteString(coloring.Coloring(field.Key, cyan, f.colorful))
+		buffer.WriteByte('=')
+		field.writeLogfmt(buffer, time.RFC3339Milli)
+	}
+}
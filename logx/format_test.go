@@ -0,0 +1,197 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+type nilStringer struct{ name string }
+
+func (s *nilStringer) String() string { return s.name }
+
+func newTestEntry(fields Fields) *Entry {
+	logger := NewLogx("test", WithInstance("test-0"))
+	entry := NewEntry(logger).WithFields(fields)
+	entry.Time = time.Date(2022, time.April, 8, 13, 11, 13, 123456789, time.UTC)
+	entry.Level = InfoLevel
+	entry.Message = "hello world"
+	return entry
+}
+
+func TestJSONFormatter(t *testing.T) {
+	var stringer *nilStringer
+	entry := newTestEntry(Fields{
+		"err":      errors.New("boom"),
+		"at":       time.Date(2022, time.April, 8, 0, 0, 0, 0, time.UTC),
+		"cost":     1500 * time.Millisecond,
+		"nil":      stringer,
+		"count":    3,
+		"msg":      "collide",
+		"ch":       make(chan int),
+		"embedded": map[string]int{"a": 1},
+	})
+
+	data, err := NewJSONFormatter().Format(entry)
+	if err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+	if !bytes.HasSuffix(data, []byte("}\n")) {
+		t.Errorf("expected one object per line, got %q", data)
+	}
+	var actual map[string]interface{}
+	if err := json.Unmarshal(data, &actual); err != nil {
+		t.Fatalf("invalid json %q: %v", data, err)
+	}
+
+	expected := map[string]interface{}{
+		"time":       "2022-04-08T13:11:13.123Z",
+		"level":      "info",
+		"name":       "test",
+		"instance":   "test-0",
+		"msg":        "hello world",
+		"err":        "boom",
+		"at":         "2022-04-08T00:00:00Z",
+		"cost":       "1.5s",
+		"count":      float64(3),
+		"fields.msg": "collide",
+	}
+	for k, v := range expected {
+		if actual[k] != v {
+			t.Errorf("key %s: expected %v, actual %v", k, v, actual[k])
+		}
+	}
+	if _, ok := actual["nil"].(string); !ok {
+		t.Errorf("nil stringer should be serialized as string, actual %v", actual["nil"])
+	}
+	if _, ok := actual["ch"].(string); !ok {
+		t.Errorf("unsupported type should fall back to string, actual %v", actual["ch"])
+	}
+}
+
+func TestJSONFormatterNested(t *testing.T) {
+	entry := newTestEntry(Fields{"user": "tom"})
+	formatter := &JSONFormatter{MessageKey: "message", FieldsKey: "fields", TimestampFormat: "2006-01-02"}
+
+	data, err := formatter.Format(entry)
+	if err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+	expected := `{"message":"hello world","fields":{"user":"tom"}}` + "\n"
+	if string(data) != expected {
+		t.Errorf("expected %s, actual %s", expected, data)
+	}
+}
+
+func TestTextFormatterFields(t *testing.T) {
+	entry := newTestEntry(Fields{"b": "has space", "a": 1, "c": "say \"hi\"\n"})
+
+	data, err := NewTextFormatter(entry.Logger, false).Format(entry)
+	if err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+	expected := `2022-04-08 13:11:13  INFO - hello world a=1 b="has space" c="say \"hi\"\n"` + "\n"
+	if string(data) != expected {
+		t.Errorf("expected %q, actual %q", expected, data)
+	}
+}
+
+func TestLogfmtFormatter(t *testing.T) {
+	entry := newTestEntry(Fields{"user": "tom", "err": errors.New("not found"), "empty": ""})
+
+	data, err := NewLogfmtFormatter().Format(entry)
+	if err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+	expected := `time=2022-04-08T13:11:13.123Z level=info name=test instance=test-0 msg="hello world" ` +
+		`empty="" err="not found" user=tom` + "\n"
+	if string(data) != expected {
+		t.Errorf("expected %q, actual %q", expected, data)
+	}
+}
+
+func TestPatternFormatter(t *testing.T) {
+	entry := newTestEntry(Fields{"requestId": "r-1", "user": "tom", "cost": "1s"})
+	entry.Caller = &runtime.Frame{File: "/app/handler.go", Line: 42, Function: "github.com/app/api.(*Server).handle"}
+
+	tests := []struct {
+		name     string
+		layout   string
+		expected string
+	}{
+		{"default", "%time %level [%caller] %msg %fields", "2022-04-08 13:11:13 INFO [handler.go:42] hello world cost=1s requestId=r-1 user=tom\n"},
+		{"instance first", "%instance %time{15:04:05.000} %level{pad} %msg", "test-0 13:11:13.123  INFO hello world\n"},
+		{"function", "%name|%level{lower}|%file:%line|%func|%msg", "test|info|handler.go:42|api.(*Server).handle|hello world\n"},
+		{"field", "[%field{requestId}] %msg %fields%%\n", "[r-1] hello world cost=1s user=tom%\n"},
+	}
+	for _, test := range tests {
+		formatter, err := NewPatternFormatter(test.layout, false)
+		if err != nil {
+			t.Fatalf("Test %s: compile error: %v", test.name, err)
+		}
+		actual, _ := formatter.Format(entry)
+		if string(actual) != test.expected {
+			t.Errorf("Test %s: expected %q, actual %q", test.name, test.expected, actual)
+		}
+	}
+
+	for _, layout := range []string{"%unknown", "%time{", "%field", "%level{wide}"} {
+		if _, err := NewPatternFormatter(layout, false); err == nil {
+			t.Errorf("expected compile error of %q", layout)
+		}
+	}
+}
+
+func TestTypedFields(t *testing.T) {
+	entry := newTestEntry(Fields{"user": "tom"})
+	entry.TypedFields = []Field{
+		String("path", "/a b"),
+		Int("status", 200),
+		Float64("ratio", 0.5),
+		Bool("ok", true),
+		Duration("cost", 1500*time.Millisecond),
+		Time("at", time.Date(2022, time.April, 8, 0, 0, 0, 0, time.UTC)),
+		Err(errors.New("boom")),
+		Err(nil),
+		Any("size", uint32(7)),
+	}
+
+	data, _ := NewLogfmtFormatter().Format(entry)
+	expected := `time=2022-04-08T13:11:13.123Z level=info name=test instance=test-0 msg="hello world" user=tom ` +
+		`path="/a b" status=200 ratio=0.5 ok=true cost=1.5s at=2022-04-08T00:00:00Z error=<nil> size=7` + "\n"
+	if string(data) != expected {
+		t.Errorf("expected %q, actual %q", expected, data)
+	}
+
+	data, _ = (&JSONFormatter{FieldsKey: "fields"}).Format(entry)
+	expected = `{"fields":{"user":"tom","path":"/a b","status":200,"ratio":0.5,"ok":true,"cost":"1.5s",` +
+		`"at":"2022-04-08T00:00:00Z","error":null,"size":7}}` + "\n"
+	if string(data) != expected {
+		t.Errorf("expected %q, actual %q", expected, data)
+	}
+
+	if fields := entry.AllFields(); fields["status"] != int64(200) || fields["user"] != "tom" {
+		t.Errorf("expected merged fields, actual %v", fields)
+	}
+
+	// every key is written once, typed fields override map fields which override Err
+	entry.Fields = Fields{"user": "tom", "status": "ok"}
+	entry.TypedFields = []Field{Int("status", 200), String("user", "jerry"), String("error", "typed")}
+	entry.Err = errors.New("boom")
+	data, _ = (&JSONFormatter{ErrorKey: "error"}).Format(entry)
+	expected = `{"status":200,"user":"jerry","error":"typed"}` + "\n"
+	if string(data) != expected {
+		t.Errorf("expected %q, actual %q", expected, data)
+	}
+	for _, formatter := range []Formatter{NewLogfmtFormatter(), NewTextFormatter(nil, false)} {
+		data, _ = formatter.Format(entry)
+		if line := string(data); strings.Count(line, "status=") != 1 || !strings.Contains(line, "status=200") ||
+			strings.Count(line, "user=") != 1 || !strings.Contains(line, "user=jerry") {
+			t.Errorf("expected each field written once by %T, actual %q", formatter, line)
+		}
+	}
+}
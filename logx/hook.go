@@ -1,30 +1,310 @@
 package logx
 
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
 type Hook interface {
 	SetLogger(logger *LogX)
 	Fire(entry *Entry) error
+	// Levels is checked on every entry, so the levels of a hook can change at runtime, see LevelSet.
 	Levels() []Level
 }
 
-func (l *LogX) AddHook(hook Hook) {
+// HookErrorHandler is called when a hook fails or an entry is dropped by an async hook.
+type HookErrorHandler func(hook Hook, entry *Entry, err error)
+
+// ErrHookQueueFull is passed to the HookErrorHandler when an async hook drops an entry.
+var ErrHookQueueFull = errors.New("hook queue is full")
+
+func defaultHookErrorHandler(hook Hook, entry *Entry, err error) {
+	_, _ = fmt.Fprintf(os.Stderr, "Failed to fire hook %T, %v\n", hook, err)
+}
+
+type HookOption func(h *registeredHook)
+
+// AsyncHook fires the hook in a background goroutine, the entries are queued up to size.
+// When the queue is full an entry waits at most timeout for space before it is dropped.
+func AsyncHook(size int, timeout time.Duration) HookOption {
+	return func(h *registeredHook) {
+		if size <= 0 {
+			size = 1024
+		}
+		h.queue = make(chan *Entry, size)
+		h.timeout = timeout
+	}
+}
+
+type registeredHook struct {
+	hook Hook
+
+	// queue is never closed since fire may race with stop, stopping ends the goroutine instead
+	queue    chan *Entry
+	timeout  time.Duration
+	stopping chan struct{}
+	done     chan struct{}
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending int
+}
+
+func (h *registeredHook) enabled(level Level) bool {
+	for _, l := range h.hook.Levels() {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *registeredHook) fire(entry *Entry, handler HookErrorHandler) {
+	if h.queue == nil {
+		if err := h.hook.Fire(entry); err != nil {
+			handler(h.hook, entry, err)
+		}
+		return
+	}
+
+	// the hook is being removed, the entries logged meanwhile are dropped
+	select {
+	case <-h.stopping:
+		return
+	default:
+	}
+
+	// the entry is released after logging, so the queue holds a copy
+	c := entry.clone()
+	h.mu.Lock()
+	h.pending++
+	h.mu.Unlock()
+
+	select {
+	case h.queue <- c:
+		return
+	default:
+	}
+	if h.timeout > 0 {
+		timer := time.NewTimer(h.timeout)
+		defer timer.Stop()
+		select {
+		case h.queue <- c:
+			return
+		case <-h.stopping:
+			h.finish()
+			return
+		case <-timer.C:
+		}
+	}
+	h.finish()
+	handler(h.hook, entry, ErrHookQueueFull)
+}
+
+func (h *registeredHook) run(handler func() HookErrorHandler) {
+	defer close(h.done)
+	for {
+		select {
+		case entry := <-h.queue:
+			h.fireQueued(entry, handler)
+		case <-h.stopping:
+			// fire the entries queued before stop
+			for {
+				select {
+				case entry := <-h.queue:
+					h.fireQueued(entry, handler)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (h *registeredHook) fireQueued(entry *Entry, handler func() HookErrorHandler) {
+	if err := h.hook.Fire(entry); err != nil {
+		handler()(h.hook, entry, err)
+	}
+	h.finish()
+}
+
+func (h *registeredHook) finish() {
+	h.mu.Lock()
+	h.pending--
+	h.cond.Broadcast()
+	h.mu.Unlock()
+}
+
+// flush waits until the queued entries are fired.
+func (h *registeredHook) flush() {
+	if h.queue == nil {
+		return
+	}
+	h.mu.Lock()
+	for h.pending > 0 {
+		h.cond.Wait()
+	}
+	h.mu.Unlock()
+}
+
+// stop fires the queued entries and stops the goroutine.
+func (h *registeredHook) stop() {
+	if h.queue == nil {
+		return
+	}
+	h.flush()
+	close(h.stopping)
+	<-h.done
+}
+
+// AddHook registers the hook, it is fired synchronously unless AsyncHook is given.
+func (l *LogX) AddHook(hook Hook, opts ...HookOption) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	l.addHookLocked(hook, opts...)
+}
 
+func (l *LogX) addHookLocked(hook Hook, opts ...HookOption) {
 	hook.SetLogger(l)
 
-	for _, level := range hook.Levels() {
-		if l.hooks[level] == nil {
-			l.hooks[level] = []Hook{}
+	h := &registeredHook{hook: hook}
+	h.cond = sync.NewCond(&h.mu)
+	for _, o := range opts {
+		o(h)
+	}
+	if h.queue != nil {
+		h.stopping = make(chan struct{})
+		h.done = make(chan struct{})
+		go h.run(l.hookErrorHandler)
+	}
+
+	hooks := l.loadHooks()
+	l.hooks.Store(append(hooks[:len(hooks):len(hooks)], h))
+}
+
+// RemoveHook unregisters the hook, the queued entries of an async hook are fired before it returns.
+func (l *LogX) RemoveHook(hook Hook) {
+	l.mu.Lock()
+	removed := l.removeHooksLocked([]Hook{hook})
+	l.mu.Unlock()
+
+	for _, h := range removed {
+		h.stop()
+	}
+}
+
+// ReplaceHooks unregisters all the hooks of the logger and registers the given ones.
+func (l *LogX) ReplaceHooks(hooks ...Hook) {
+	l.mu.Lock()
+	var all []Hook
+	for _, h := range l.loadHooks() {
+		all = append(all, h.hook)
+	}
+	removed := l.removeHooksLocked(all)
+	for _, hook := range hooks {
+		l.addHookLocked(hook)
+	}
+	l.mu.Unlock()
+
+	for _, h := range removed {
+		h.stop()
+	}
+}
+
+// removeHooksLocked returns the removed hooks, they must be stopped after l.mu is released.
+func (l *LogX) removeHooksLocked(hooks []Hook) []*registeredHook {
+	var kept, removed []*registeredHook
+	for _, h := range l.loadHooks() {
+		if containsHook(hooks, h.hook) {
+			removed = append(removed, h)
+		} else {
+			kept = append(kept, h)
 		}
-		l.hooks[level] = append(l.hooks[level], hook)
 	}
+	l.hooks.Store(kept)
+	return removed
 }
 
-func (l *LogX) fireHooks(level Level, entry *Entry) error {
-	for _, hs := range l.hooks[level] {
-		if err := hs.Fire(entry); err != nil {
-			return err
+func containsHook(hooks []Hook, hook Hook) bool {
+	for _, h := range hooks {
+		if h == hook {
+			return true
 		}
 	}
-	return nil
+	return false
+}
+
+// loadHooks returns the registered hooks, the slice is never modified in place.
+func (l *LogX) loadHooks() []*registeredHook {
+	hooks, _ := l.hooks.Load().([]*registeredHook)
+	return hooks
+}
+
+// SetHookErrorHandler sets the handler of hook errors, the default one writes to stderr.
+func (l *LogX) SetHookErrorHandler(handler HookErrorHandler) {
+	l.errorHandler.Store(handler)
+}
+
+func WithHookErrorHandler(handler HookErrorHandler) Option {
+	return func(l *LogX) {
+		l.SetHookErrorHandler(handler)
+	}
+}
+
+func (l *LogX) hookErrorHandler() HookErrorHandler {
+	for logger := l; logger != nil; logger = logger.parent {
+		if handler, ok := logger.errorHandler.Load().(HookErrorHandler); ok && handler != nil {
+			return handler
+		}
+	}
+	return defaultHookErrorHandler
+}
+
+// fireHooks fires the hooks of the logger and its ancestors, a failed hook doesn't stop the others.
+func (l *LogX) fireHooks(level Level, entry *Entry) {
+	var handler HookErrorHandler
+	for logger := l; logger != nil; logger = logger.parent {
+		for _, h := range logger.loadHooks() {
+			if !h.enabled(level) {
+				continue
+			}
+			if handler == nil {
+				handler = l.hookErrorHandler()
+			}
+			h.fire(entry, handler)
+		}
+	}
+}
+
+// flushHooks waits for the queued entries of the async hooks.
+func (l *LogX) flushHooks() {
+	for logger := l; logger != nil; logger = logger.parent {
+		for _, h := range logger.loadHooks() {
+			h.flush()
+		}
+	}
+}
+
+// LevelSet is a set of levels which can be changed while logging, hooks can use it to implement Levels.
+type LevelSet struct {
+	levels atomic.Value
+}
+
+func NewLevelSet(levels ...Level) *LevelSet {
+	s := &LevelSet{}
+	s.SetLevels(levels...)
+	return s
+}
+
+func (s *LevelSet) Levels() []Level {
+	levels, _ := s.levels.Load().([]Level)
+	return levels
+}
+
+func (s *LevelSet) SetLevels(levels ...Level) {
+	s.levels.Store(append([]Level(nil), levels...))
 }
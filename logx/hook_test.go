@@ -0,0 +1,171 @@
+package logx
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type failHook struct {
+	*LevelSet
+}
+
+func (h *failHook) SetLogger(*LogX) {}
+
+func (h *failHook) Fire(*Entry) error { return errors.New("broken") }
+
+// slowHook blocks until released.
+type slowHook struct {
+	captureHook
+	release chan struct{}
+}
+
+func (h *slowHook) Fire(entry *Entry) error {
+	<-h.release
+	return h.captureHook.Fire(entry)
+}
+
+func TestHookErrorHandler(t *testing.T) {
+	var failures []string
+	fail := &failHook{LevelSet: NewLevelSet(ErrorLevel)}
+	capture := &captureHook{}
+	logger := NewLogx("test", WithOutput(nil), WithHook(fail), WithHook(capture),
+		WithHookErrorHandler(func(hook Hook, entry *Entry, err error) {
+			failures = append(failures, entry.Message+": "+err.Error())
+		}))
+
+	logger.Error("first")
+	if len(failures) != 1 || failures[0] != "first: broken" {
+		t.Errorf("expected error handled, actual %v", failures)
+	}
+	if len(capture.messages()) != 1 {
+		t.Errorf("expected the remaining hooks fired after a failure")
+	}
+
+	// levels can change at runtime
+	fail.SetLevels(WarnLevel)
+	logger.Error("second")
+	logger.Warn("third")
+	if len(failures) != 2 || failures[1] != "third: broken" {
+		t.Errorf("expected levels changed at runtime, actual %v", failures)
+	}
+
+	logger.RemoveHook(fail)
+	logger.Warn("fourth")
+	if len(failures) != 2 {
+		t.Errorf("expected removed hook not fired, actual %v", failures)
+	}
+}
+
+func TestAsyncHook(t *testing.T) {
+	var mu sync.Mutex
+	var dropped int
+	hook := &slowHook{release: make(chan struct{})}
+	logger := NewLogx("test", WithOutput(nil), WithHookErrorHandler(func(hook Hook, entry *Entry, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if errors.Is(err, ErrHookQueueFull) {
+			dropped++
+		}
+	}))
+	logger.AddHook(hook, AsyncHook(2, time.Millisecond))
+
+	// not blocked by the slow hook: one in flight, two queued, the rest dropped
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		logger.WithField("i", i).Info("async")
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("expected logging not blocked by async hook")
+	}
+	close(hook.release)
+	_ = logger.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if fired := len(hook.messages()); fired+dropped != 5 || fired < 2 {
+		t.Errorf("expected all entries fired or dropped, fired %d dropped %d", fired, dropped)
+	}
+	if hook.entries[0].Fields["i"] != 0 {
+		t.Errorf("expected a copy of the entry queued, actual %v", hook.entries[0].Fields)
+	}
+	logger.RemoveHook(hook)
+}
+
+func TestAddHookWhileLogging(t *testing.T) {
+	logger := NewLogx("test", WithOutput(nil))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			logger.Info("racing")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		hook := &captureHook{}
+		logger.AddHook(hook)
+		logger.RemoveHook(hook)
+	}
+	<-done
+}
+
+func TestRemoveAsyncHookWhileLogging(t *testing.T) {
+	logger := NewLogx("test", WithOutput(nil))
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					logger.Info("racing")
+				}
+			}
+		}()
+	}
+	for start := time.Now(); time.Since(start) < 200*time.Millisecond; {
+		hook := &captureHook{}
+		logger.AddHook(hook, AsyncHook(1, time.Millisecond))
+		time.Sleep(time.Microsecond)
+		logger.ReplaceHooks()
+	}
+	close(stop)
+	wg.Wait()
+}
+
+// gateHook blocks in Levels, which fireHooks calls after it loaded the hooks, until proceed is closed.
+type gateHook struct {
+	captureHook
+	once    sync.Once
+	inside  chan struct{}
+	proceed chan struct{}
+}
+
+func (h *gateHook) Levels() []Level {
+	h.once.Do(func() {
+		close(h.inside)
+		<-h.proceed
+	})
+	return AllLevels()
+}
+
+func TestFireRemovedAsyncHook(t *testing.T) {
+	logger := NewLogx("test", WithOutput(nil))
+	hook := &gateHook{inside: make(chan struct{}), proceed: make(chan struct{})}
+	logger.AddHook(hook, AsyncHook(1, time.Millisecond))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.Info("racing")
+	}()
+	<-hook.inside
+	logger.RemoveHook(hook)
+	close(hook.proceed)
+	<-done
+}
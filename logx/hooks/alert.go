@@ -0,0 +1,426 @@
+package hooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/xiaorui77/goutils/logx"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	stdtime "time"
+)
+
+func init() {
+	// used by logx config files: {"type": "alert", "options": {"url": "https://oapi.dingtalk.com/robot/send?access_token=x", "template": "dingtalk", "secret": "SEC..."}}
+	logx.RegisterHookFactory("alert", func(options json.RawMessage) (logx.Hook, error) {
+		var opts struct {
+			URL       string `json:"url"`
+			Template  string `json:"template"`
+			Secret    string `json:"secret"`
+			Level     string `json:"level"`
+			Throttle  string `json:"throttle"`
+			Aggregate string `json:"aggregate"`
+		}
+		if err := json.Unmarshal(options, &opts); err != nil {
+			return nil, fmt.Errorf("invalid alert options: %v", err)
+		}
+		var template AlertTemplate
+		switch strings.ToLower(opts.Template) {
+		case "dingtalk":
+			template = DingTalkTemplate(opts.Secret)
+		case "feishu":
+			template = FeishuTemplate(opts.Secret)
+		case "slack":
+			template = SlackTemplate()
+		case "", "json":
+			template = JSONTemplate()
+		default:
+			return nil, fmt.Errorf("unknown alert template %q", opts.Template)
+		}
+		var hookOpts []AlertOption
+		if opts.Level != "" {
+			level, ok := logx.LookupLevel(opts.Level)
+			if !ok {
+				return nil, fmt.Errorf("unknown level %q", opts.Level)
+			}
+			hookOpts = append(hookOpts, WithAlertLevel(level))
+		}
+		for _, d := range []struct {
+			value  string
+			option func(stdtime.Duration) AlertOption
+		}{{opts.Throttle, WithAlertThrottle}, {opts.Aggregate, WithAlertAggregate}} {
+			if d.value == "" {
+				continue
+			}
+			duration, err := stdtime.ParseDuration(d.value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid alert duration %q: %v", d.value, err)
+			}
+			hookOpts = append(hookOpts, d.option(duration))
+		}
+		return NewAlertHook(opts.URL, template, hookOpts...)
+	})
+}
+
+// Alert is the message sent for one entry, or for the entries of the same key in a throttle or aggregation window.
+type Alert struct {
+	Key       string      `json:"key"`
+	Level     logx.Level  `json:"level"`
+	App       string      `json:"app"`
+	Instance  string      `json:"instance"`
+	Message   string      `json:"message"`
+	RequestId string      `json:"requestId,omitempty"`
+	Fields    logx.Fields `json:"fields,omitempty"`
+	// Time is the time of the first entry, Count is the number of entries.
+	Time  stdtime.Time `json:"time"`
+	Count int          `json:"count"`
+}
+
+// Text renders the alert as plain text for chat messages.
+func (a *Alert) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s %s\n%s\n", strings.ToUpper(a.Level.String()), a.App, a.Instance, a.Message)
+	if a.RequestId != "" {
+		fmt.Fprintf(&b, "requestId: %s\n", a.RequestId)
+	}
+	for _, k := range sortedFieldKeys(a.Fields) {
+		fmt.Fprintf(&b, "%s: %s\n", k, fieldString(a.Fields[k]))
+	}
+	if a.Count > 1 {
+		fmt.Fprintf(&b, "occurred %d times since %s", a.Count, a.Time.Format("2006-01-02 15:04:05"))
+	} else {
+		b.WriteString(a.Time.Format("2006-01-02 15:04:05"))
+	}
+	return b.String()
+}
+
+// AlertTemplate builds the request which posts the alert to the webhook url.
+type AlertTemplate func(url string, alert *Alert) (*http.Request, error)
+
+func newJSONRequest(url string, body interface{}) (*http.Request, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// DingTalkTemplate posts a text message to a DingTalk robot, the url is signed if secret is not empty.
+func DingTalkTemplate(secret string) AlertTemplate {
+	return func(webhook string, alert *Alert) (*http.Request, error) {
+		if secret != "" {
+			timestamp := strconv.FormatInt(stdtime.Now().UnixMilli(), 10)
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write([]byte(timestamp + "\n" + secret))
+			sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+			sep := "?"
+			if strings.Contains(webhook, "?") {
+				sep = "&"
+			}
+			webhook += sep + "timestamp=" + timestamp + "&sign=" + url.QueryEscape(sign)
+		}
+		return newJSONRequest(webhook, map[string]interface{}{
+			"msgtype": "text",
+			"text":    map[string]string{"content": alert.Text()},
+		})
+	}
+}
+
+// FeishuTemplate posts a text message to a Feishu bot, the body is signed if secret is not empty.
+func FeishuTemplate(secret string) AlertTemplate {
+	return func(webhook string, alert *Alert) (*http.Request, error) {
+		body := map[string]interface{}{
+			"msg_type": "text",
+			"content":  map[string]string{"text": alert.Text()},
+		}
+		if secret != "" {
+			timestamp := strconv.FormatInt(stdtime.Now().Unix(), 10)
+			mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+			body["timestamp"] = timestamp
+			body["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		}
+		return newJSONRequest(webhook, body)
+	}
+}
+
+// SlackTemplate posts a message to a Slack incoming webhook.
+func SlackTemplate() AlertTemplate {
+	return func(webhook string, alert *Alert) (*http.Request, error) {
+		return newJSONRequest(webhook, map[string]string{"text": alert.Text()})
+	}
+}
+
+// JSONTemplate posts the Alert as json.
+func JSONTemplate() AlertTemplate {
+	return func(webhook string, alert *Alert) (*http.Request, error) {
+		return newJSONRequest(webhook, alert)
+	}
+}
+
+type AlertOption func(h *AlertHook)
+
+// WithAlertLevel sends the entries at or above the level, defaults to ErrorLevel.
+func WithAlertLevel(level logx.Level) AlertOption {
+	return func(h *AlertHook) {
+		h.minLevel = level
+	}
+}
+
+// WithAlertThrottle sends at most one alert per key in the interval, the entries in between
+// are sent as one alert with their count when the interval ends.
+func WithAlertThrottle(interval stdtime.Duration) AlertOption {
+	return func(h *AlertHook) {
+		h.throttle = interval
+	}
+}
+
+// WithAlertAggregate holds the first entry of a key for the window and sends it with the count of the
+// entries of the key in the window.
+func WithAlertAggregate(window stdtime.Duration) AlertOption {
+	return func(h *AlertHook) {
+		h.aggregate = window
+	}
+}
+
+// WithAlertKey sets the key of throttling and aggregation, defaults to the logger name, level and message.
+func WithAlertKey(key func(entry *logx.Entry) string) AlertOption {
+	return func(h *AlertHook) {
+		h.key = key
+	}
+}
+
+// WithAlertQueueSize sets the number of alerts waiting to be sent, defaults to 256.
+func WithAlertQueueSize(size int) AlertOption {
+	return func(h *AlertHook) {
+		h.queueSize = size
+	}
+}
+
+func WithAlertHTTPClient(client *http.Client) AlertOption {
+	return func(h *AlertHook) {
+		h.client = client
+	}
+}
+
+// AlertHook sends the entries to a chat webhook in a background goroutine. Fire never blocks,
+// an alert is dropped with an error if the queue is full.
+type AlertHook struct {
+	url       string
+	template  AlertTemplate
+	minLevel  logx.Level
+	levels    []logx.Level
+	throttle  stdtime.Duration
+	aggregate stdtime.Duration
+	key       func(entry *logx.Entry) string
+	queueSize int
+	client    *http.Client
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan *Alert
+	done   chan struct{}
+
+	// keys is only accessed by the goroutine
+	keys map[string]*alertState
+}
+
+type alertState struct {
+	sentAt  stdtime.Time
+	pending *Alert
+	due     stdtime.Time
+}
+
+func NewAlertHook(url string, template AlertTemplate, opts ...AlertOption) (*AlertHook, error) {
+	if url == "" {
+		return nil, fmt.Errorf("url is required for alert hook")
+	}
+	if template == nil {
+		return nil, fmt.Errorf("template is required for alert hook")
+	}
+	h := &AlertHook{
+		url:       url,
+		template:  template,
+		minLevel:  logx.ErrorLevel,
+		key:       defaultAlertKey,
+		queueSize: 256,
+		client:    &http.Client{Timeout: 10 * stdtime.Second},
+		keys:      map[string]*alertState{},
+	}
+	for _, o := range opts {
+		o(h)
+	}
+	h.levels = logx.LevelsFrom(h.minLevel)
+	h.queue = make(chan *Alert, h.queueSize)
+	h.done = make(chan struct{})
+	go h.run()
+	return h, nil
+}
+
+func defaultAlertKey(entry *logx.Entry) string {
+	var name string
+	if entry.Logger != nil {
+		name = entry.Logger.Name
+	}
+	return name + "|" + entry.Level.String() + "|" + entry.Message
+}
+
+func (h *AlertHook) SetLogger(*logx.LogX) {}
+
+func (h *AlertHook) Levels() []logx.Level {
+	return h.levels
+}
+
+func (h *AlertHook) Fire(entry *logx.Entry) error {
+	alert := &Alert{
+		Key:     h.key(entry),
+		Level:   entry.Level,
+		Message: entry.Message,
+		Fields:  logx.Fields{},
+		Time:    entry.Time,
+		Count:   1,
+	}
+	if entry.Logger != nil {
+		alert.App, alert.Instance = entry.Logger.Name, entry.Logger.Instance
+	}
+	for k, v := range entry.AllFields() {
+		alert.Fields[k] = v
+	}
+	if id, ok := alert.Fields[string(logx.RequestIdKey)]; ok {
+		alert.RequestId = fieldString(id)
+		delete(alert.Fields, string(logx.RequestIdKey))
+	} else if entry.Context != nil {
+		if id := entry.Context.Value(logx.RequestIdKey); id != nil {
+			alert.RequestId = fieldString(id)
+		}
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		return fmt.Errorf("alert: hook is closed")
+	}
+	select {
+	case h.queue <- alert:
+		return nil
+	default:
+		return fmt.Errorf("alert: queue is full, alert dropped")
+	}
+}
+
+func (h *AlertHook) run() {
+	defer close(h.done)
+
+	timer := stdtime.NewTimer(stdtime.Hour)
+	stopTimer(timer)
+	for {
+		select {
+		case alert, ok := <-h.queue:
+			if !ok {
+				// send the held alerts before stopping
+				for _, s := range h.keys {
+					if s.pending != nil {
+						h.send(s.pending)
+					}
+				}
+				return
+			}
+			h.add(alert, stdtime.Now())
+		case <-timer.C:
+		}
+		h.sendDue(stdtime.Now())
+
+		stopTimer(timer)
+		if next, ok := h.nextDue(); ok {
+			timer.Reset(stdtime.Until(next))
+		}
+	}
+}
+
+// add sends the alert or holds it by the throttle and aggregation of its key.
+func (h *AlertHook) add(alert *Alert, now stdtime.Time) {
+	s := h.keys[alert.Key]
+	if s == nil {
+		s = &alertState{}
+		h.keys[alert.Key] = s
+	}
+	if s.pending != nil {
+		s.pending.Count++
+		return
+	}
+
+	due := now.Add(h.aggregate)
+	if h.throttle > 0 && !s.sentAt.IsZero() && s.sentAt.Add(h.throttle).After(due) {
+		due = s.sentAt.Add(h.throttle)
+	}
+	if due.After(now) {
+		s.pending, s.due = alert, due
+		return
+	}
+	h.send(alert)
+	s.sentAt = now
+}
+
+// sendDue sends the held alerts whose window ended and forgets the idle keys.
+func (h *AlertHook) sendDue(now stdtime.Time) {
+	for key, s := range h.keys {
+		if s.pending != nil && !s.due.After(now) {
+			h.send(s.pending)
+			s.pending, s.sentAt = nil, now
+		}
+		if s.pending == nil && !s.sentAt.Add(h.throttle).After(now) {
+			delete(h.keys, key)
+		}
+	}
+}
+
+func (h *AlertHook) nextDue() (stdtime.Time, bool) {
+	var next stdtime.Time
+	for _, s := range h.keys {
+		if s.pending != nil && (next.IsZero() || s.due.Before(next)) {
+			next = s.due
+		}
+	}
+	return next, !next.IsZero()
+}
+
+func (h *AlertHook) send(alert *Alert) {
+	req, err := h.template(h.url, alert)
+	if err == nil {
+		var resp *http.Response
+		if resp, err = h.client.Do(req); err == nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			if resp.StatusCode/100 != 2 {
+				err = fmt.Errorf("status %s", resp.Status)
+			}
+		}
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to send alert, %v\n", err)
+	}
+}
+
+// Close sends the queued and held alerts and stops the hook, the entries fired later return an error.
+func (h *AlertHook) Close() error {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.queue)
+	}
+	h.mu.Unlock()
+	<-h.done
+	return nil
+}
@@ -0,0 +1,224 @@
+package hooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/xiaorui77/goutils/logx"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWebhook records the requests, it blocks until release is closed if release is set.
+type fakeWebhook struct {
+	mu      sync.Mutex
+	release chan struct{}
+	queries []url.Values
+	bodies  [][]byte
+}
+
+func (f *fakeWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.release != nil {
+		<-f.release
+	}
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries = append(f.queries, r.URL.Query())
+	f.bodies = append(f.bodies, body)
+}
+
+func (f *fakeWebhook) alerts(t *testing.T) []Alert {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	alerts := make([]Alert, len(f.bodies))
+	for i, body := range f.bodies {
+		if err := json.Unmarshal(body, &alerts[i]); err != nil {
+			t.Fatalf("invalid alert %s: %v", body, err)
+		}
+	}
+	return alerts
+}
+
+func newAlertLogger(hook *AlertHook) *logx.LogX {
+	return logx.NewLogx("app", logx.WithInstance("app-1"), logx.WithOutput(nil), logx.WithoutEnv(),
+		logx.WithHook(hook))
+}
+
+func TestAlertHook(t *testing.T) {
+	webhook := &fakeWebhook{}
+	server := httptest.NewServer(webhook)
+	defer server.Close()
+
+	hook, err := NewAlertHook(server.URL, JSONTemplate())
+	if err != nil {
+		t.Fatalf("NewAlertHook() error: %v", err)
+	}
+	logger := newAlertLogger(hook)
+	ctx := context.WithValue(context.Background(), logx.RequestIdKey, "req-1")
+	logger.WithContext(ctx).WithField("table", "users").Error("query failed")
+	logger.Warnf("slow query")
+	if err := hook.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	alerts := webhook.alerts(t)
+	if len(alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(alerts))
+	}
+	a := alerts[0]
+	if a.Message != "query failed" || a.Level != logx.ErrorLevel || a.App != "app" || a.Instance != "app-1" ||
+		a.RequestId != "req-1" || a.Fields["table"] != "users" || a.Count != 1 {
+		t.Errorf("unexpected alert %+v", a)
+	}
+	if _, ok := a.Fields["requestId"]; ok {
+		t.Errorf("requestId is kept in fields: %v", a.Fields)
+	}
+	if err := hook.Fire(&logx.Entry{Level: logx.ErrorLevel}); err == nil {
+		t.Errorf("Fire() after Close() returns no error")
+	}
+}
+
+func TestAlertHookThrottle(t *testing.T) {
+	webhook := &fakeWebhook{}
+	server := httptest.NewServer(webhook)
+	defer server.Close()
+
+	hook, _ := NewAlertHook(server.URL, JSONTemplate(), WithAlertThrottle(100*time.Millisecond))
+	logger := newAlertLogger(hook)
+	for i := 0; i < 5; i++ {
+		logger.Errorf("connection refused")
+	}
+	logger.Errorf("disk full")
+	time.Sleep(300 * time.Millisecond)
+
+	alerts := webhook.alerts(t)
+	counts := map[string][]int{}
+	for _, a := range alerts {
+		counts[a.Message] = append(counts[a.Message], a.Count)
+	}
+	if got := counts["connection refused"]; len(got) != 2 || got[0] != 1 || got[1] != 4 {
+		t.Errorf("connection refused counts = %v, want [1 4]", got)
+	}
+	if got := counts["disk full"]; len(got) != 1 || got[0] != 1 {
+		t.Errorf("disk full counts = %v, want [1]", got)
+	}
+	_ = hook.Close()
+}
+
+func TestAlertHookAggregate(t *testing.T) {
+	webhook := &fakeWebhook{}
+	server := httptest.NewServer(webhook)
+	defer server.Close()
+
+	hook, _ := NewAlertHook(server.URL, JSONTemplate(), WithAlertAggregate(time.Hour),
+		WithAlertKey(func(entry *logx.Entry) string { return entry.Level.String() }))
+	logger := newAlertLogger(hook)
+	logger.Errorf("first")
+	logger.Errorf("second")
+	logger.Errorf("third")
+	time.Sleep(50 * time.Millisecond)
+	if n := len(webhook.alerts(t)); n != 0 {
+		t.Fatalf("got %d alerts in the window, want 0", n)
+	}
+
+	// Close sends the held alert
+	_ = hook.Close()
+	alerts := webhook.alerts(t)
+	if len(alerts) != 1 || alerts[0].Message != "first" || alerts[0].Count != 3 {
+		t.Errorf("unexpected alerts %+v", alerts)
+	}
+}
+
+func TestAlertHookNonBlocking(t *testing.T) {
+	webhook := &fakeWebhook{release: make(chan struct{})}
+	server := httptest.NewServer(webhook)
+	defer server.Close()
+
+	hook, _ := NewAlertHook(server.URL, JSONTemplate(), WithAlertQueueSize(1))
+	var dropped int
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		if hook.Fire(&logx.Entry{Level: logx.ErrorLevel, Message: strings.Repeat("x", i+1)}) != nil {
+			dropped++
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Fire() blocked for %v", elapsed)
+	}
+	if dropped == 0 {
+		t.Errorf("no alert dropped with a full queue")
+	}
+	close(webhook.release)
+	_ = hook.Close()
+}
+
+func TestAlertTemplates(t *testing.T) {
+	alert := &Alert{Level: logx.ErrorLevel, App: "app", Instance: "app-1", Message: "query failed",
+		RequestId: "req-1", Fields: logx.Fields{"table": "users"}, Time: time.Now(), Count: 3}
+
+	t.Run("dingtalk", func(t *testing.T) {
+		req, err := DingTalkTemplate("SECret")("https://oapi.dingtalk.com/robot/send?access_token=x", alert)
+		if err != nil {
+			t.Fatalf("template error: %v", err)
+		}
+		query := req.URL.Query()
+		mac := hmac.New(sha256.New, []byte("SECret"))
+		mac.Write([]byte(query.Get("timestamp") + "\nSECret"))
+		if query.Get("access_token") != "x" || query.Get("sign") != base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+			t.Errorf("unexpected query %v", query)
+		}
+		var body struct {
+			Msgtype string `json:"msgtype"`
+			Text    struct {
+				Content string `json:"content"`
+			} `json:"text"`
+		}
+		data, _ := io.ReadAll(req.Body)
+		_ = json.Unmarshal(data, &body)
+		for _, s := range []string{"[ERROR]", "query failed", "requestId: req-1", "table: users", "occurred 3 times"} {
+			if body.Msgtype != "text" || !strings.Contains(body.Text.Content, s) {
+				t.Errorf("body %s does not contain %q", data, s)
+			}
+		}
+	})
+
+	t.Run("feishu", func(t *testing.T) {
+		req, _ := FeishuTemplate("SECret")("https://open.feishu.cn/open-apis/bot/v2/hook/x", alert)
+		var body struct {
+			MsgType   string `json:"msg_type"`
+			Timestamp string `json:"timestamp"`
+			Sign      string `json:"sign"`
+			Content   struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		}
+		data, _ := io.ReadAll(req.Body)
+		_ = json.Unmarshal(data, &body)
+		mac := hmac.New(sha256.New, []byte(body.Timestamp+"\nSECret"))
+		if body.MsgType != "text" || body.Sign != base64.StdEncoding.EncodeToString(mac.Sum(nil)) ||
+			!strings.Contains(body.Content.Text, "query failed") {
+			t.Errorf("unexpected body %s", data)
+		}
+	})
+
+	t.Run("slack", func(t *testing.T) {
+		req, _ := SlackTemplate()("https://hooks.slack.com/services/x", alert)
+		var body struct {
+			Text string `json:"text"`
+		}
+		data, _ := io.ReadAll(req.Body)
+		_ = json.Unmarshal(data, &body)
+		if !strings.Contains(body.Text, "query failed") || req.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected body %s", data)
+		}
+	})
+}
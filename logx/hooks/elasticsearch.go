@@ -2,65 +2,370 @@ package hooks
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
 	"github.com/olivere/elastic/v7"
 	"github.com/xiaorui77/goutils/logx"
 	"github.com/xiaorui77/goutils/time"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	stdtime "time"
+	"unicode"
 )
 
-var hookLevels = []logx.Level{
-	logx.InfoLevel,
-	logx.WarnLevel,
-	logx.ErrorLevel,
-	logx.FatalLevel,
-	logx.PanicLevel,
+func init() {
+	// used by logx config files: {"type": "elasticsearch", "options": {"url": "http://127.0.0.1:9200"}}
+	logx.RegisterHookFactory("elasticsearch", func(options json.RawMessage) (logx.Hook, error) {
+		var opts struct {
+			URL           string `json:"url"`
+			Username      string `json:"username"`
+			Password      string `json:"password"`
+			APIKey        string `json:"apiKey"`
+			IndexPrefix   string `json:"indexPrefix"`
+			Level         string `json:"level"`
+			Sniff         bool   `json:"sniff"`
+			BulkActions   int    `json:"bulkActions"`
+			FlushInterval string `json:"flushInterval"`
+			Workers       int    `json:"workers"`
+		}
+		if err := json.Unmarshal(options, &opts); err != nil {
+			return nil, fmt.Errorf("invalid elasticsearch options: %v", err)
+		}
+		if opts.URL == "" {
+			return nil, fmt.Errorf("url is required for elasticsearch hook")
+		}
+		hookOpts := []EsOption{WithEsURL(opts.URL), WithEsSniff(opts.Sniff)}
+		if opts.Username != "" {
+			hookOpts = append(hookOpts, WithEsBasicAuth(opts.Username, opts.Password))
+		}
+		if opts.APIKey != "" {
+			hookOpts = append(hookOpts, WithEsAPIKey(opts.APIKey))
+		}
+		if opts.IndexPrefix != "" {
+			prefix := opts.IndexPrefix
+			hookOpts = append(hookOpts, func(h *EsHook) {
+				h.indexPrefix = prefix
+			})
+		}
+		if opts.Level != "" {
+			level, ok := logx.LookupLevel(opts.Level)
+			if !ok {
+				return nil, fmt.Errorf("unknown level %q", opts.Level)
+			}
+			hookOpts = append(hookOpts, WithEsLevel(level))
+		}
+		if opts.BulkActions > 0 {
+			hookOpts = append(hookOpts, WithEsBulkActions(opts.BulkActions))
+		}
+		if opts.FlushInterval != "" {
+			interval, err := stdtime.ParseDuration(opts.FlushInterval)
+			if err != nil {
+				return nil, fmt.Errorf("invalid elasticsearch flushInterval %q: %v", opts.FlushInterval, err)
+			}
+			hookOpts = append(hookOpts, WithEsFlushInterval(interval))
+		}
+		if opts.Workers > 0 {
+			hookOpts = append(hookOpts, WithEsWorkers(opts.Workers))
+		}
+		return NewEsHook(hookOpts...)
+	})
+}
+
+// EsFullPolicy decides what Fire does when the buffer of the hook is full.
+type EsFullPolicy int
+
+const (
+	// EsBlockWhenFull waits until the entry is buffered, it's the default.
+	EsBlockWhenFull EsFullPolicy = iota
+	// EsDropWhenFull drops the entry and counts it in EsStats.Dropped.
+	EsDropWhenFull
+)
+
+type EsOption func(h *EsHook)
+
+// WithEsURL sets the urls of the nodes, defaults to http://127.0.0.1:9200.
+func WithEsURL(urls ...string) EsOption {
+	return func(h *EsHook) {
+		h.urls = urls
+	}
+}
+
+func WithEsBasicAuth(username, password string) EsOption {
+	return func(h *EsHook) {
+		h.clientOpts = append(h.clientOpts, elastic.SetBasicAuth(username, password))
+	}
+}
+
+// WithEsAPIKey authenticates with the base64 encoded api key returned by elasticsearch.
+func WithEsAPIKey(key string) EsOption {
+	return func(h *EsHook) {
+		h.clientOpts = append(h.clientOpts, elastic.SetHeaders(http.Header{"Authorization": {"ApiKey " + key}}))
+	}
+}
+
+func WithEsTLSConfig(config *tls.Config) EsOption {
+	return func(h *EsHook) {
+		h.tlsConfig = config
+	}
+}
+
+// WithEsSniff finds the other nodes of the cluster from the urls, disabled by default
+// since the nodes are often unreachable behind a proxy or in containers.
+func WithEsSniff(enabled bool) EsOption {
+	return func(h *EsHook) {
+		h.sniff = enabled
+	}
+}
+
+// WithEsLevel indexes the entries at or above the level, defaults to InfoLevel.
+func WithEsLevel(level logx.Level) EsOption {
+	return func(h *EsHook) {
+		h.minLevel = level
+	}
+}
+
+// WithEsIndex sets the index of the entry, defaults to "logx_" and the name of the logger the hook is added to,
+// made a valid index name. The entries of the Named children go to the index of that logger.
+func WithEsIndex(index func(entry *logx.Entry) string) EsOption {
+	return func(h *EsHook) {
+		h.index = index
+	}
+}
+
+// WithEsClientOptions passes more options to the elastic client.
+func WithEsClientOptions(opts ...elastic.ClientOptionFunc) EsOption {
+	return func(h *EsHook) {
+		h.clientOpts = append(h.clientOpts, opts...)
+	}
+}
+
+// WithEsBulkActions commits a bulk request after the number of entries, defaults to 1000.
+func WithEsBulkActions(n int) EsOption {
+	return func(h *EsHook) {
+		h.bulkActions = n
+	}
+}
+
+// WithEsBulkSize commits a bulk request after the number of bytes, defaults to 5MB.
+func WithEsBulkSize(bytes int) EsOption {
+	return func(h *EsHook) {
+		h.bulkSize = bytes
+	}
+}
+
+// WithEsFlushInterval commits the buffered entries periodically, defaults to 1s.
+func WithEsFlushInterval(interval stdtime.Duration) EsOption {
+	return func(h *EsHook) {
+		h.flushInterval = interval
+	}
 }
 
-type esHook struct {
-	logger *logx.LogX
-	client *elastic.Client
+// WithEsWorkers sets the number of goroutines committing bulk requests, defaults to 1.
+func WithEsWorkers(n int) EsOption {
+	return func(h *EsHook) {
+		h.workers = n
+	}
+}
+
+// WithEsBuffer sets the number of entries waiting for the bulk processor and the policy when they are full,
+// defaults to 1000 and EsBlockWhenFull.
+func WithEsBuffer(size int, policy EsFullPolicy) EsOption {
+	return func(h *EsHook) {
+		h.bufferSize = size
+		h.fullPolicy = policy
+	}
+}
+
+// EsStats counts the entries of an elasticsearch hook.
+type EsStats struct {
+	Fired   int64 // entries passed to Fire
+	Dropped int64 // entries dropped because the buffer was full
+	Indexed int64 // entries indexed successfully
+	Failed  int64 // entries rejected by elasticsearch or lost in a failed bulk request
+	Commits int64 // bulk requests committed
+}
+
+// EsHook indexes the entries into elasticsearch with the bulk api in background goroutines.
+type EsHook struct {
+	urls       []string
+	clientOpts []elastic.ClientOptionFunc
+	tlsConfig  *tls.Config
+	sniff      bool
+	minLevel   logx.Level
+	levels     []logx.Level
+	index      func(entry *logx.Entry) string
+	// indexPrefix and the name of logger make the default index, logger holds the *logx.LogX set by SetLogger
+	indexPrefix string
+	logger      atomic.Value
+
+	bulkActions   int
+	bulkSize      int
+	flushInterval stdtime.Duration
+	workers       int
+	bufferSize    int
+	fullPolicy    EsFullPolicy
+
+	client    *elastic.Client
+	processor *elastic.BulkProcessor
+	// cancel aborts the bulk requests in flight when Close runs out of time
 	ctx    context.Context
+	cancel context.CancelFunc
+
+	// closing is closed first by Close to release the Fire calls blocked by EsBlockWhenFull,
+	// then mu is locked to close buff once no Fire is sending
+	closing   chan struct{}
+	closeOnce sync.Once
+	mu        sync.RWMutex
+	closed    bool
+	buff      chan elastic.BulkableRequest
+	done      chan struct{}
 
-	buff       chan *LogDoc
-	errCount   int
-	totalCount int
+	fired   int64
+	dropped int64
+	indexed int64
+	failed  int64
+	commits int64
 }
 
-func NewEsHook(host string) *esHook {
-	client, err := elastic.NewClient(
-		elastic.SetURL(host),
-		elastic.SetSniff(false),
-	)
+// NewEsHook connects to elasticsearch and starts the bulk processor, call Close to index the buffered entries.
+func NewEsHook(opts ...EsOption) (*EsHook, error) {
+	h := &EsHook{
+		urls:          []string{elastic.DefaultURL},
+		minLevel:      logx.InfoLevel,
+		indexPrefix:   "logx_",
+		bulkActions:   1000,
+		bulkSize:      5 << 20,
+		flushInterval: stdtime.Second,
+		workers:       1,
+		bufferSize:    1000,
+	}
+	for _, o := range opts {
+		o(h)
+	}
+	h.levels = logx.LevelsFrom(h.minLevel)
+	if h.index == nil {
+		h.index = h.defaultIndex
+	}
+	if h.bufferSize <= 0 {
+		return nil, fmt.Errorf("elasticsearch buffer size must be positive")
+	}
+
+	clientOpts := []elastic.ClientOptionFunc{elastic.SetURL(h.urls...), elastic.SetSniff(h.sniff)}
+	if h.tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = h.tlsConfig
+		clientOpts = append(clientOpts, elastic.SetHttpClient(&http.Client{Transport: transport}))
+	}
+	client, err := elastic.NewClient(append(clientOpts, h.clientOpts...)...)
 	if err != nil {
-		logx.Fatalf("failed to create Elastic V7 Client: %v", err)
-		return nil
+		return nil, fmt.Errorf("failed to create elasticsearch client: %v", err)
 	}
-	h := &esHook{
-		client: client,
-		ctx:    context.Background(),
-		buff:   make(chan *LogDoc, 1000),
+	h.client = client
+	h.ctx, h.cancel = context.WithCancel(context.Background())
+	h.processor, err = client.BulkProcessor().Name("logx").
+		BulkActions(h.bulkActions).
+		BulkSize(h.bulkSize).
+		FlushInterval(h.flushInterval).
+		Workers(h.workers).
+		After(h.after).
+		Do(h.ctx)
+	if err != nil {
+		h.cancel()
+		client.Stop()
+		return nil, fmt.Errorf("failed to start elasticsearch bulk processor: %v", err)
 	}
+	h.buff = make(chan elastic.BulkableRequest, h.bufferSize)
+	h.closing = make(chan struct{})
+	h.done = make(chan struct{})
 	go h.run()
-	return h
+	return h, nil
+}
+
+// defaultIndex is named after the logger the hook is added to, or the logger of the entry before it is added.
+func (hook *EsHook) defaultIndex(entry *logx.Entry) string {
+	logger, ok := hook.logger.Load().(*logx.LogX)
+	if !ok {
+		logger = entry.Logger
+	}
+	return esIndexName(hook.indexPrefix + logger.Name)
+}
+
+// esIndexName lowercases name and replaces the characters elasticsearch doesn't allow in an index name
+// with '_', the leading '-', '_' and '+' are removed.
+func esIndexName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '\\', '/', '*', '?', '"', '<', '>', '|', ',', '#', ':', ' ':
+			return '_'
+		}
+		return unicode.ToLower(r)
+	}, name)
+	name = strings.TrimLeft(name, "-_+")
+	if name == "" || name == "." || name == ".." {
+		return "logx"
+	}
+	return name
 }
 
-func (hook *esHook) Fire(entry *logx.Entry) error {
-	hook.buff <- &LogDoc{
+func (hook *EsHook) Fire(entry *logx.Entry) error {
+	fields := logx.Fields{}
+	for k, v := range entry.AllFields() {
+		fields[k] = v
+	}
+	req := elastic.NewBulkIndexRequest().Index(hook.index(entry)).Doc(&LogDoc{
 		App:       entry.Logger.Name,
 		Instance:  entry.Logger.Instance,
 		Level:     entry.Level.String(),
 		Message:   entry.Message,
-		Fields:    entry.Fields,
+		Fields:    fields,
 		Timestamp: entry.Time.Format(time.RFC3339Milli),
+	})
+
+	hook.mu.RLock()
+	defer hook.mu.RUnlock()
+	if hook.closed {
+		return fmt.Errorf("elasticsearch: hook is closed")
+	}
+	atomic.AddInt64(&hook.fired, 1)
+	if hook.fullPolicy == EsBlockWhenFull {
+		select {
+		case hook.buff <- req:
+			return nil
+		case <-hook.closing:
+			atomic.AddInt64(&hook.dropped, 1)
+			return fmt.Errorf("elasticsearch: hook is closed")
+		}
+	}
+	select {
+	case hook.buff <- req:
+		return nil
+	default:
+		atomic.AddInt64(&hook.dropped, 1)
+		return fmt.Errorf("elasticsearch: buffer is full, entry dropped")
 	}
-	return nil
 }
 
-func (hook *esHook) Levels() []logx.Level {
-	return hookLevels
+func (hook *EsHook) Levels() []logx.Level {
+	return hook.levels
 }
 
-func (hook *esHook) SetLogger(logger *logx.LogX) {
-	hook.logger = logger
+func (hook *EsHook) SetLogger(logger *logx.LogX) {
+	hook.logger.Store(logger)
+}
+
+// Stats returns the counters of the entries since the hook was created.
+func (hook *EsHook) Stats() EsStats {
+	return EsStats{
+		Fired:   atomic.LoadInt64(&hook.fired),
+		Dropped: atomic.LoadInt64(&hook.dropped),
+		Indexed: atomic.LoadInt64(&hook.indexed),
+		Failed:  atomic.LoadInt64(&hook.failed),
+		Commits: atomic.LoadInt64(&hook.commits),
+	}
 }
 
 type LogDoc struct {
@@ -72,20 +377,56 @@ type LogDoc struct {
 	Timestamp string                 `json:"timestamp"`
 }
 
-func (hook *esHook) run() {
-	for {
-		select {
-		case <-hook.ctx.Done():
-			return
-		case l := <-hook.buff:
-			hook.send(l)
+// run passes the buffered entries to the bulk processor, whose Add blocks while the workers are committing.
+// It commits the rest and stops the processor once the buffer is closed.
+func (hook *EsHook) run() {
+	defer close(hook.done)
+	for req := range hook.buff {
+		hook.processor.Add(req)
+	}
+	if err := hook.processor.Close(); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to flush logs, %v\n", err)
+	}
+	hook.client.Stop()
+}
+
+func (hook *EsHook) after(_ int64, requests []elastic.BulkableRequest, resp *elastic.BulkResponse, err error) {
+	atomic.AddInt64(&hook.commits, 1)
+	if err != nil {
+		atomic.AddInt64(&hook.failed, int64(len(requests)))
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to index logs, %v\n", err)
+		return
+	}
+	if resp == nil {
+		return
+	}
+	atomic.AddInt64(&hook.indexed, int64(len(resp.Succeeded())))
+	if failed := resp.Failed(); len(failed) > 0 {
+		atomic.AddInt64(&hook.failed, int64(len(failed)))
+		if failed[0].Error != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Failed to index %d logs, %s\n", len(failed), failed[0].Error.Reason)
 		}
 	}
 }
 
-func (hook *esHook) send(l *LogDoc) {
-	if _, err := hook.client.Index().Index("logx_" + hook.logger.Name).BodyJson(l).Do(hook.ctx); err != nil {
-		hook.errCount++
+// Close stops accepting entries and indexes the buffered ones. If ctx is done first, the bulk requests
+// in flight are aborted and ctx.Err() is returned, the entries not indexed yet are lost.
+// A Fire blocked by EsBlockWhenFull returns an error.
+func (hook *EsHook) Close(ctx context.Context) error {
+	hook.closeOnce.Do(func() {
+		close(hook.closing)
+		hook.mu.Lock()
+		hook.closed = true
+		close(hook.buff)
+		hook.mu.Unlock()
+	})
+
+	select {
+	case <-hook.done:
+		hook.cancel()
+		return nil
+	case <-ctx.Done():
+		hook.cancel()
+		return ctx.Err()
 	}
-	hook.totalCount++
 }
@@ -0,0 +1,318 @@
+package hooks
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"github.com/olivere/elastic/v7"
+	"github.com/xiaorui77/goutils/logx"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEs answers the ping of the client and records the documents of _bulk requests,
+// the documents whose message contains "reject" fail with status 400.
+type fakeEs struct {
+	mu      sync.Mutex
+	release chan struct{}
+	bulks   int
+	auth    []string
+	indices []string
+	docs    []LogDoc
+}
+
+func (f *fakeEs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path != "/_bulk" {
+		_, _ = fmt.Fprint(w, `{"name":"fake","cluster_name":"fake","version":{"number":"7.17.0"}}`)
+		return
+	}
+	if f.release != nil {
+		<-f.release
+	}
+
+	var items []string
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(nil, 1<<20)
+	f.mu.Lock()
+	f.bulks++
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+	for scanner.Scan() {
+		var action struct {
+			Index struct {
+				Index string `json:"_index"`
+			} `json:"index"`
+		}
+		_ = json.Unmarshal(scanner.Bytes(), &action)
+		if !scanner.Scan() {
+			break
+		}
+		var doc LogDoc
+		_ = json.Unmarshal(scanner.Bytes(), &doc)
+		f.indices = append(f.indices, action.Index.Index)
+		f.docs = append(f.docs, doc)
+		if strings.Contains(doc.Message, "reject") {
+			items = append(items, fmt.Sprintf(`{"index":{"_index":%q,"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}`, action.Index.Index))
+		} else {
+			items = append(items, fmt.Sprintf(`{"index":{"_index":%q,"_id":"%d","result":"created","status":201}}`, action.Index.Index, len(f.docs)))
+		}
+	}
+	f.mu.Unlock()
+	_, _ = fmt.Fprintf(w, `{"took":1,"errors":%t,"items":[%s]}`, len(items) > 0, strings.Join(items, ","))
+}
+
+func (f *fakeEs) messages() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var messages []string
+	for _, doc := range f.docs {
+		messages = append(messages, doc.Message)
+	}
+	return messages
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met in 5s")
+		}
+	}
+}
+
+func newTestEsHook(t *testing.T, url string, opts ...EsOption) *EsHook {
+	t.Helper()
+	hook, err := NewEsHook(append([]EsOption{WithEsURL(url)}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewEsHook() error: %v", err)
+	}
+	return hook
+}
+
+func TestEsHookBulk(t *testing.T) {
+	es := &fakeEs{}
+	server := httptest.NewServer(es)
+	defer server.Close()
+
+	hook := newTestEsHook(t, server.URL, WithEsBulkActions(3), WithEsFlushInterval(50*time.Millisecond),
+		WithEsBasicAuth("elastic", "secret"))
+	logger := logx.NewLogx("app", logx.WithInstance("app-1"), logx.WithOutput(nil), logx.WithoutEnv(),
+		logx.WithHook(hook))
+	logger.WithField("n", 1).Info("first")
+	logger.Info("second")
+	logger.Info("reject me")
+	logger.Info("fourth")
+	logger.Debug("not indexed")
+
+	waitFor(t, func() bool { return hook.Stats().Commits >= 2 })
+	if got := es.messages(); strings.Join(got, ",") != "first,second,reject me,fourth" {
+		t.Errorf("indexed %v", got)
+	}
+	es.mu.Lock()
+	if es.bulks != 2 || es.auth[0] != "Basic ZWxhc3RpYzpzZWNyZXQ=" || es.indices[0] != "logx_app" || es.docs[0].Instance != "app-1" || es.docs[0].Fields["n"] != 1.0 {
+		t.Errorf("unexpected bulks %d, indices %v, docs %+v", es.bulks, es.indices, es.docs)
+	}
+	es.mu.Unlock()
+	if stats := hook.Stats(); stats != (EsStats{Fired: 4, Indexed: 3, Failed: 1, Commits: 2}) {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestDefaultEsIndex(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"app", "logx_app"},
+		{"MyApp", "logx_myapp"},
+		{"api/v1 *gateway?", "logx_api_v1__gateway_"},
+		{`a\b"c<d>e|f,g#h:i`, "logx_a_b_c_d_e_f_g_h_i"},
+	}
+	hook := &EsHook{indexPrefix: "logx_"}
+	for _, test := range tests {
+		if actual := hook.defaultIndex(&logx.Entry{Logger: logx.NewLogx(test.name, logx.WithoutEnv())}); actual != test.expected {
+			t.Errorf("Test %s: expected %q, actual %q", test.name, test.expected, actual)
+		}
+	}
+
+	// the children share the index of the logger the hook is added to
+	logger := logx.NewLogx("app", logx.WithoutEnv(), logx.WithHook(hook))
+	if actual := hook.defaultIndex(&logx.Entry{Logger: logger.Named("db").Named("pool")}); actual != "logx_app" {
+		t.Errorf("expected index of the hook logger, actual %q", actual)
+	}
+	if actual := esIndexName("-_+Logs"); actual != "logs" {
+		t.Errorf("expected leading characters removed, actual %q", actual)
+	}
+}
+
+func TestEsHookBufferFull(t *testing.T) {
+	es := &fakeEs{release: make(chan struct{})}
+	server := httptest.NewServer(es)
+	defer server.Close()
+	defer close(es.release)
+
+	entry := func(msg string) *logx.Entry {
+		return &logx.Entry{Logger: logx.NewLogx("app", logx.WithoutEnv()), Level: logx.InfoLevel, Message: msg}
+	}
+
+	t.Run("drop", func(t *testing.T) {
+		hook := newTestEsHook(t, server.URL, WithEsBulkActions(1), WithEsBuffer(2, EsDropWhenFull))
+		start := time.Now()
+		var errs int
+		for i := 0; i < 10; i++ {
+			if hook.Fire(entry("drop")) != nil {
+				errs++
+			}
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Fire() blocked for %v", elapsed)
+		}
+		if stats := hook.Stats(); errs == 0 || stats.Dropped != int64(errs) || stats.Fired != 10 {
+			t.Errorf("%d errors, Stats() = %+v", errs, stats)
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		// blocking is the default policy
+		hook := newTestEsHook(t, server.URL, WithEsBulkActions(1))
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 1010; i++ {
+				_ = hook.Fire(entry("block"))
+			}
+		}()
+		select {
+		case <-done:
+			t.Fatalf("Fire() did not block with a full buffer")
+		case <-time.After(100 * time.Millisecond):
+		}
+		if stats := hook.Stats(); stats.Dropped != 0 {
+			t.Errorf("Stats() = %+v", stats)
+		}
+	})
+}
+
+func TestEsHookClose(t *testing.T) {
+	es := &fakeEs{}
+	server := httptest.NewServer(es)
+	defer server.Close()
+
+	hook := newTestEsHook(t, server.URL, WithEsAPIKey("a2V5"), WithEsFlushInterval(time.Hour),
+		WithEsIndex(func(entry *logx.Entry) string { return "logs-" + entry.Level.String() }), WithEsLevel(logx.WarnLevel))
+	logger := logx.NewLogx("app", logx.WithOutput(nil), logx.WithoutEnv(), logx.WithHook(hook))
+	logger.Warn("first")
+	logger.Error("second")
+	logger.Info("not indexed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hook.Close(ctx); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if got := es.messages(); strings.Join(got, ",") != "first,second" {
+		t.Errorf("indexed %v", got)
+	}
+	es.mu.Lock()
+	if len(es.auth) != 1 || es.auth[0] != "ApiKey a2V5" || es.indices[0] != "logs-warn" || es.indices[1] != "logs-error" {
+		t.Errorf("unexpected auth %v, indices %v", es.auth, es.indices)
+	}
+	es.mu.Unlock()
+	if err := hook.Fire(&logx.Entry{Logger: logger, Level: logx.ErrorLevel}); err == nil {
+		t.Errorf("Fire() after Close() returns no error")
+	}
+	if err := hook.Close(ctx); err != nil {
+		t.Errorf("second Close() error: %v", err)
+	}
+}
+
+func TestEsHookCloseDeadline(t *testing.T) {
+	es := &fakeEs{release: make(chan struct{})}
+	server := httptest.NewServer(es)
+	defer server.Close()
+	defer close(es.release)
+
+	hook := newTestEsHook(t, server.URL, WithEsBulkActions(1))
+	logger := logx.NewLogx("app", logx.WithOutput(nil), logx.WithoutEnv(), logx.WithHook(hook))
+	logger.Info("stuck")
+	logger.Info("buffered")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := hook.Close(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Close() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Close() returned after %v", elapsed)
+	}
+}
+
+func TestEsHookCloseBlockedFire(t *testing.T) {
+	es := &fakeEs{release: make(chan struct{})}
+	server := httptest.NewServer(es)
+	defer server.Close()
+	defer close(es.release)
+
+	hook := newTestEsHook(t, server.URL, WithEsBulkActions(1), WithEsBuffer(1, EsBlockWhenFull))
+	logger := logx.NewLogx("app", logx.WithOutput(nil), logx.WithoutEnv())
+	errs := make(chan error, 10)
+	go func() {
+		for i := 0; i < 10; i++ {
+			errs <- hook.Fire(&logx.Entry{Logger: logger, Level: logx.InfoLevel, Message: "blocked"})
+		}
+		close(errs)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_ = hook.Close(ctx)
+	var failed int
+	for err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	// the blocked Fire is dropped, the later ones are rejected
+	if stats := hook.Stats(); failed == 0 || stats.Dropped != 1 {
+		t.Errorf("expected blocked Fire() released by Close(), %d errors, Stats() = %+v", failed, stats)
+	}
+}
+
+func TestEsHookTLS(t *testing.T) {
+	es := &fakeEs{}
+	server := httptest.NewTLSServer(es)
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	hook := newTestEsHook(t, server.URL, WithEsTLSConfig(&tls.Config{RootCAs: pool}))
+	logger := logx.NewLogx("app", logx.WithOutput(nil), logx.WithoutEnv(), logx.WithHook(hook))
+	logger.Info("over tls")
+	if err := hook.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if got := es.messages(); len(got) != 1 || got[0] != "over tls" {
+		t.Errorf("indexed %v", got)
+	}
+}
+
+func TestNewEsHookError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	if _, err := NewEsHook(WithEsURL(server.URL),
+		WithEsClientOptions(elastic.SetHealthcheckTimeoutStartup(100*time.Millisecond))); err == nil {
+		t.Errorf("NewEsHook() with an unreachable node returns no error")
+	}
+	if _, err := NewEsHook(WithEsBuffer(0, EsDropWhenFull)); err == nil {
+		t.Errorf("NewEsHook() with an empty buffer returns no error")
+	}
+}
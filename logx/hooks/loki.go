@@ -0,0 +1,495 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/snappy"
+	"github.com/xiaorui77/goutils/logx"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	stdtime "time"
+)
+
+func init() {
+	// used by logx config files: {"type": "loki", "options": {"url": "http://127.0.0.1:3100", "labels": ["module"]}}
+	logx.RegisterHookFactory("loki", func(options json.RawMessage) (logx.Hook, error) {
+		var opts struct {
+			URL       string   `json:"url"`
+			Labels    []string `json:"labels"`
+			BatchSize int      `json:"batchSize"`
+			BatchWait string   `json:"batchWait"`
+			Protobuf  bool     `json:"protobuf"`
+			Tenant    string   `json:"tenant"`
+			Level     string   `json:"level"`
+		}
+		if err := json.Unmarshal(options, &opts); err != nil {
+			return nil, fmt.Errorf("invalid loki options: %v", err)
+		}
+		hookOpts := []LokiOption{WithLokiLabels(opts.Labels...), WithLokiTenant(opts.Tenant)}
+		if opts.BatchSize > 0 {
+			hookOpts = append(hookOpts, WithLokiBatchSize(opts.BatchSize))
+		}
+		if opts.BatchWait != "" {
+			wait, err := stdtime.ParseDuration(opts.BatchWait)
+			if err != nil {
+				return nil, fmt.Errorf("invalid loki batchWait %q: %v", opts.BatchWait, err)
+			}
+			hookOpts = append(hookOpts, WithLokiBatchWait(wait))
+		}
+		if opts.Protobuf {
+			hookOpts = append(hookOpts, WithLokiProtobuf())
+		}
+		if opts.Level != "" {
+			level, ok := logx.LookupLevel(opts.Level)
+			if !ok {
+				return nil, fmt.Errorf("unknown level %q", opts.Level)
+			}
+			hookOpts = append(hookOpts, WithLokiLevel(level))
+		}
+		return NewLokiHook(opts.URL, hookOpts...)
+	})
+}
+
+const lokiPushPath = "/loki/api/v1/push"
+
+type LokiOption func(h *LokiHook)
+
+// WithLokiLabels adds the fields of the keys to the stream labels, besides app, instance and level.
+// Keep the keys few and their values bounded, every distinct label set is a stream in Loki.
+func WithLokiLabels(keys ...string) LokiOption {
+	return func(h *LokiHook) {
+		for _, k := range keys {
+			h.labelKeys[k] = struct{}{}
+		}
+	}
+}
+
+// WithLokiBatchSize sends a batch once it has size entries, defaults to 1000.
+func WithLokiBatchSize(size int) LokiOption {
+	return func(h *LokiHook) {
+		h.batchSize = size
+	}
+}
+
+// WithLokiBatchWait sends a batch at most wait after its first entry, defaults to 1 second.
+func WithLokiBatchWait(wait stdtime.Duration) LokiOption {
+	return func(h *LokiHook) {
+		h.batchWait = wait
+	}
+}
+
+// WithLokiProtobuf pushes snappy compressed protobuf instead of json.
+func WithLokiProtobuf() LokiOption {
+	return func(h *LokiHook) {
+		h.protobuf = true
+	}
+}
+
+// WithLokiRetry retries a batch up to max times on network errors, 429 and 5xx,
+// the delay doubles from min up to 5 seconds. Defaults to 5 retries from 200ms.
+func WithLokiRetry(max int, min stdtime.Duration) LokiOption {
+	return func(h *LokiHook) {
+		h.maxRetries, h.minBackoff = max, min
+	}
+}
+
+// WithLokiTenant sets the X-Scope-OrgID header of a multi-tenant Loki.
+func WithLokiTenant(tenant string) LokiOption {
+	return func(h *LokiHook) {
+		h.tenant = tenant
+	}
+}
+
+// WithLokiLevel sends the entries at or above the level, defaults to InfoLevel.
+func WithLokiLevel(level logx.Level) LokiOption {
+	return func(h *LokiHook) {
+		h.minLevel = level
+	}
+}
+
+// WithLokiFormatter sets the formatter of the log line, the label fields are removed from the entry before.
+// Defaults to a json object of the message, caller, error and the remaining fields.
+func WithLokiFormatter(formatter logx.Formatter) LokiOption {
+	return func(h *LokiHook) {
+		h.formatter = formatter
+	}
+}
+
+func WithLokiHTTPClient(client *http.Client) LokiOption {
+	return func(h *LokiHook) {
+		h.client = client
+	}
+}
+
+// LokiHook batches the entries and pushes them to Loki in a background goroutine.
+// Fire never blocks, an entry is dropped with an error if the queue is full.
+type LokiHook struct {
+	url        string
+	labelKeys  map[string]struct{}
+	batchSize  int
+	batchWait  stdtime.Duration
+	protobuf   bool
+	maxRetries int
+	minBackoff stdtime.Duration
+	tenant     string
+	minLevel   logx.Level
+	levels     []logx.Level
+	formatter  logx.Formatter
+	client     *http.Client
+
+	// cancel aborts the push in flight when Close runs out of time
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan lokiEntry
+	done   chan struct{}
+}
+
+type lokiEntry struct {
+	// key is the labels in the LogQL selector form, e.g. {app="api", level="info"}
+	key    string
+	labels map[string]string
+	time   stdtime.Time
+	line   string
+}
+
+// NewLokiHook pushes to the Loki at url, e.g. http://127.0.0.1:3100, the push path is appended if missing.
+func NewLokiHook(url string, opts ...LokiOption) (*LokiHook, error) {
+	if url == "" {
+		return nil, fmt.Errorf("url is required for loki hook")
+	}
+	if !strings.HasSuffix(url, lokiPushPath) {
+		url = strings.TrimSuffix(url, "/") + lokiPushPath
+	}
+	h := &LokiHook{
+		url:        url,
+		labelKeys:  map[string]struct{}{},
+		batchSize:  1000,
+		batchWait:  stdtime.Second,
+		maxRetries: 5,
+		minBackoff: 200 * stdtime.Millisecond,
+		minLevel:   logx.InfoLevel,
+		formatter: &logx.JSONFormatter{MessageKey: "msg", CallerKey: "caller", ErrorKey: "error",
+			StacktraceKey: "stacktrace", TimestampFormat: "2006-01-02T15:04:05.000Z07:00"},
+		client: &http.Client{Timeout: 10 * stdtime.Second},
+	}
+	for _, o := range opts {
+		o(h)
+	}
+	h.levels = logx.LevelsFrom(h.minLevel)
+	if h.batchSize <= 0 {
+		return nil, fmt.Errorf("loki batch size must be positive")
+	}
+	h.ctx, h.cancel = context.WithCancel(context.Background())
+	h.queue = make(chan lokiEntry, h.batchSize*4)
+	h.done = make(chan struct{})
+	go h.run()
+	return h, nil
+}
+
+func (h *LokiHook) SetLogger(*logx.LogX) {}
+
+func (h *LokiHook) Levels() []logx.Level {
+	return h.levels
+}
+
+func (h *LokiHook) Fire(entry *logx.Entry) error {
+	labels, line, err := h.build(entry)
+	if err != nil {
+		return err
+	}
+	e := lokiEntry{key: formatLabels(labels), labels: labels, time: entry.Time, line: line}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		return fmt.Errorf("loki: hook is closed")
+	}
+	select {
+	case h.queue <- e:
+		return nil
+	default:
+		return fmt.Errorf("loki: queue is full, entry dropped")
+	}
+}
+
+// build returns the labels and the log line, the label fields are removed from the line.
+func (h *LokiHook) build(entry *logx.Entry) (map[string]string, string, error) {
+	labels := map[string]string{"level": entry.Level.String()}
+	if entry.Logger != nil {
+		labels["app"], labels["instance"] = entry.Logger.Name, entry.Logger.Instance
+	}
+
+	e := *entry
+	e.Buffer = nil
+	if len(h.labelKeys) > 0 {
+		e.Fields = make(logx.Fields, len(entry.Fields))
+		for k, v := range entry.Fields {
+			if _, ok := h.labelKeys[k]; ok {
+				labels[labelName(k)] = fieldString(v)
+			} else {
+				e.Fields[k] = v
+			}
+		}
+		e.TypedFields = nil
+		for _, f := range entry.TypedFields {
+			if _, ok := h.labelKeys[f.Key]; ok {
+				labels[labelName(f.Key)] = fieldString(f.Value())
+			} else {
+				e.TypedFields = append(e.TypedFields, f)
+			}
+		}
+	}
+
+	line, err := h.formatter.Format(&e)
+	if err != nil {
+		return nil, "", err
+	}
+	return labels, strings.TrimSuffix(string(line), "\n"), nil
+}
+
+// formatLabels returns the labels in the LogQL selector form.
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(k + "=" + strconv.Quote(labels[k]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// labelName replaces the characters not allowed in a label name with '_'.
+func labelName(key string) string {
+	b := []byte(key)
+	for i, c := range b {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+func (h *LokiHook) run() {
+	defer close(h.done)
+
+	var batch []lokiEntry
+	timer := stdtime.NewTimer(h.batchWait)
+	stopTimer(timer)
+	send := func() {
+		if len(batch) > 0 {
+			if err := h.push(batch); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "Failed to push %d entries to loki, %v\n", len(batch), err)
+			}
+			batch = nil
+		}
+	}
+
+	for {
+		select {
+		case e, ok := <-h.queue:
+			if !ok {
+				stopTimer(timer)
+				send()
+				return
+			}
+			if len(batch) == 0 {
+				timer.Reset(h.batchWait)
+			}
+			batch = append(batch, e)
+			if len(batch) >= h.batchSize {
+				stopTimer(timer)
+				send()
+			}
+		case <-timer.C:
+			send()
+		}
+	}
+}
+
+// stopTimer stops the timer and drains a fire not received yet, so a later Reset doesn't see a stale fire.
+func stopTimer(timer *stdtime.Timer) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+}
+
+// push sends the batch, it is retried on network errors, 429 and 5xx.
+func (h *LokiHook) push(batch []lokiEntry) error {
+	body, contentType, err := h.encode(batch)
+	if err != nil {
+		return err
+	}
+
+	backoff := h.minBackoff
+	for retry := 0; ; retry++ {
+		err = h.post(body, contentType)
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(*lokiRetryable); !ok || retry >= h.maxRetries || h.ctx.Err() != nil {
+			return err
+		}
+		select {
+		case <-stdtime.After(backoff):
+		case <-h.ctx.Done():
+			return err
+		}
+		if backoff *= 2; backoff > 5*stdtime.Second {
+			backoff = 5 * stdtime.Second
+		}
+	}
+}
+
+type lokiRetryable struct {
+	err error
+}
+
+func (e *lokiRetryable) Error() string {
+	return e.err.Error()
+}
+
+func (h *LokiHook) post(body []byte, contentType string) error {
+	req, err := http.NewRequestWithContext(h.ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if h.tenant != "" {
+		req.Header.Set("X-Scope-OrgID", h.tenant)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return &lokiRetryable{err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("loki: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return &lokiRetryable{err: err}
+	}
+	return err
+}
+
+// encode groups the entries by labels, the entries of a stream keep their order.
+func (h *LokiHook) encode(batch []lokiEntry) ([]byte, string, error) {
+	var order []string
+	streams := map[string][]lokiEntry{}
+	for _, e := range batch {
+		if _, ok := streams[e.key]; !ok {
+			order = append(order, e.key)
+		}
+		streams[e.key] = append(streams[e.key], e)
+	}
+
+	if h.protobuf {
+		return snappy.Encode(nil, encodePushRequest(order, streams)), "application/x-protobuf", nil
+	}
+
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	req := struct {
+		Streams []stream `json:"streams"`
+	}{}
+	for _, key := range order {
+		s := stream{Stream: streams[key][0].labels}
+		for _, e := range streams[key] {
+			s.Values = append(s.Values, [2]string{strconv.FormatInt(e.time.UnixNano(), 10), e.line})
+		}
+		req.Streams = append(req.Streams, s)
+	}
+	body, err := json.Marshal(req)
+	return body, "application/json", err
+}
+
+// encodePushRequest encodes logproto.PushRequest:
+//
+//	message PushRequest { repeated StreamAdapter streams = 1; }
+//	message StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	message EntryAdapter { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+func encodePushRequest(order []string, streams map[string][]lokiEntry) []byte {
+	var req []byte
+	for _, key := range order {
+		stream := appendProtoBytes(nil, 1, []byte(key))
+		for _, e := range streams[key] {
+			var ts []byte
+			if sec := e.time.Unix(); sec != 0 {
+				ts = appendProtoVarint(ts, 1, uint64(sec))
+			}
+			if nsec := e.time.Nanosecond(); nsec != 0 {
+				ts = appendProtoVarint(ts, 2, uint64(nsec))
+			}
+			entry := appendProtoBytes(nil, 1, ts)
+			entry = appendProtoBytes(entry, 2, []byte(e.line))
+			stream = appendProtoBytes(stream, 2, entry)
+		}
+		req = appendProtoBytes(req, 1, stream)
+	}
+	return req
+}
+
+func appendProtoVarint(b []byte, field int, v uint64) []byte {
+	b = appendVarint(b, uint64(field)<<3)
+	return appendVarint(b, v)
+}
+
+func appendProtoBytes(b []byte, field int, v []byte) []byte {
+	b = appendVarint(b, uint64(field)<<3|2)
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// Close sends the queued entries and stops the hook, the entries fired later return an error.
+// If ctx is done first, the push in flight is aborted and ctx.Err() is returned, the entries not pushed yet are lost.
+func (h *LokiHook) Close(ctx context.Context) error {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.queue)
+	}
+	h.mu.Unlock()
+
+	select {
+	case <-h.done:
+		h.cancel()
+		return nil
+	case <-ctx.Done():
+		h.cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,184 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/golang/snappy"
+	"github.com/xiaorui77/goutils/logx"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type lokiPush struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+// fakeLoki records the pushes, the first failures requests are answered with status.
+type fakeLoki struct {
+	mu       sync.Mutex
+	failures int
+	status   int
+	requests int
+	bodies   [][]byte
+	types    []string
+}
+
+func (f *fakeLoki) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+	if r.URL.Path != "/loki/api/v1/push" || r.Header.Get("X-Scope-OrgID") != "team" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if f.failures > 0 {
+		f.failures--
+		w.WriteHeader(f.status)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	f.bodies = append(f.bodies, body)
+	f.types = append(f.types, r.Header.Get("Content-Type"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeLoki) pushes() [][]byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]byte(nil), f.bodies...)
+}
+
+func TestLokiHook(t *testing.T) {
+	loki := &fakeLoki{failures: 2, status: http.StatusTooManyRequests}
+	server := httptest.NewServer(loki)
+	defer server.Close()
+
+	hook, err := NewLokiHook(server.URL, WithLokiLabels("module"), WithLokiTenant("team"), WithLokiBatchSize(3),
+		WithLokiBatchWait(time.Hour), WithLokiRetry(3, time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewLokiHook() error: %v", err)
+	}
+	logger := logx.NewLogx("app", logx.WithInstance("app-1"), logx.WithOutput(nil), logx.WithoutEnv(),
+		logx.WithHook(hook))
+
+	db := logger.With(logx.Fields{"module": "db"})
+	db.WithField("table", "users").Info("query")
+	db.Warn("slow")
+	logger.Infow("request", logx.Int("status", 200))
+	logger.Debug("dropped")
+	logger.Info("flushed by close")
+
+	// the first batch is full, it is sent after the retries
+	deadline := time.Now().Add(5 * time.Second)
+	for len(loki.pushes()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	_ = hook.Close(context.Background())
+	if err := hook.Fire(&logx.Entry{Logger: logger}); err == nil {
+		t.Errorf("expected error after close")
+	}
+
+	pushes := loki.pushes()
+	if len(pushes) != 2 || loki.requests != 4 {
+		t.Fatalf("expected 2 pushes in 4 requests, actual %d in %d", len(pushes), loki.requests)
+	}
+	var push lokiPush
+	if err := json.Unmarshal(pushes[0], &push); err != nil {
+		t.Fatalf("invalid push %s: %v", pushes[0], err)
+	}
+	if len(push.Streams) != 3 {
+		t.Fatalf("expected 3 streams, actual %s", pushes[0])
+	}
+	first := push.Streams[0]
+	expected := map[string]string{"app": "app", "instance": "app-1", "level": "info", "module": "db"}
+	for k, v := range expected {
+		if first.Stream[k] != v {
+			t.Errorf("label %s: expected %q, actual %q", k, v, first.Stream[k])
+		}
+	}
+	if len(first.Values) != 1 || first.Values[0][1] != `{"msg":"query","table":"users"}` {
+		t.Errorf("expected the label field removed from the line, actual %v", first.Values)
+	}
+	if push.Streams[1].Stream["level"] != "warn" || push.Streams[2].Stream["module"] != "" {
+		t.Errorf("unexpected streams %s", pushes[0])
+	}
+
+	if err := json.Unmarshal(pushes[1], &push); err != nil || len(push.Streams) != 1 ||
+		push.Streams[0].Values[0][1] != `{"msg":"flushed by close"}` {
+		t.Errorf("expected the last entry flushed by close, actual %s", pushes[1])
+	}
+}
+
+func TestLokiHookProtobuf(t *testing.T) {
+	loki := &fakeLoki{failures: 1, status: http.StatusBadRequest}
+	server := httptest.NewServer(loki)
+	defer server.Close()
+
+	hook, err := NewLokiHook(server.URL+"/loki/api/v1/push", WithLokiTenant("team"), WithLokiProtobuf(),
+		WithLokiBatchWait(10*time.Millisecond), WithLokiRetry(3, time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewLokiHook() error: %v", err)
+	}
+	logger := logx.NewLogx("app", logx.WithOutput(nil), logx.WithoutEnv(), logx.WithHook(hook))
+
+	// 400 is not retried
+	logger.Info("rejected")
+	time.Sleep(50 * time.Millisecond)
+	logger.Info("pushed")
+	_ = hook.Close(context.Background())
+
+	pushes := loki.pushes()
+	if len(pushes) != 1 || loki.types[0] != "application/x-protobuf" {
+		t.Fatalf("expected 1 protobuf push, actual %d %v", len(pushes), loki.types)
+	}
+	data, err := snappy.Decode(nil, pushes[0])
+	if err != nil {
+		t.Fatalf("invalid snappy: %v", err)
+	}
+	for _, s := range []string{`{app="app", instance="app-0", level="info"}`, `{"msg":"pushed"}`} {
+		if !bytes.Contains(data, []byte(s)) {
+			t.Errorf("expected %q in %q", s, data)
+		}
+	}
+}
+
+func TestLokiHookCloseDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	hook, err := NewLokiHook(server.URL, WithLokiBatchSize(1))
+	if err != nil {
+		t.Fatalf("NewLokiHook() error: %v", err)
+	}
+	logger := logx.NewLogx("app", logx.WithOutput(nil), logx.WithoutEnv(), logx.WithHook(hook))
+	logger.Info("stuck")
+	logger.Info("queued")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := hook.Close(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Close() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Close() returned after %v", elapsed)
+	}
+	// the push in flight is aborted
+	select {
+	case <-hook.done:
+	case <-time.After(5 * time.Second):
+		t.Errorf("expected the hook stopped after Close() timed out")
+	}
+}
@@ -0,0 +1,508 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/xiaorui77/goutils/logx"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	stdtime "time"
+)
+
+func init() {
+	// used by logx config files: {"type": "syslog", "options": {"network": "udp", "addr": "127.0.0.1:514", "format": "rfc5424", "facility": "local0"}}
+	logx.RegisterHookFactory("syslog", func(options json.RawMessage) (logx.Hook, error) {
+		var opts struct {
+			Network  string `json:"network"`
+			Addr     string `json:"addr"`
+			Format   string `json:"format"`
+			Facility string `json:"facility"`
+			Level    string `json:"level"`
+		}
+		if err := json.Unmarshal(options, &opts); err != nil {
+			return nil, fmt.Errorf("invalid syslog options: %v", err)
+		}
+		var hookOpts []SyslogOption
+		switch strings.ToLower(opts.Format) {
+		case "":
+		case "rfc5424":
+			hookOpts = append(hookOpts, WithSyslogFormat(RFC5424))
+		case "rfc3164":
+			hookOpts = append(hookOpts, WithSyslogFormat(RFC3164))
+		default:
+			return nil, fmt.Errorf("unknown syslog format %q", opts.Format)
+		}
+		if opts.Facility != "" {
+			facility, ok := facilities[strings.ToLower(opts.Facility)]
+			if !ok {
+				return nil, fmt.Errorf("unknown syslog facility %q", opts.Facility)
+			}
+			hookOpts = append(hookOpts, WithSyslogFacility(facility))
+		}
+		if opts.Level != "" {
+			level, ok := logx.LookupLevel(opts.Level)
+			if !ok {
+				return nil, fmt.Errorf("unknown level %q", opts.Level)
+			}
+			hookOpts = append(hookOpts, WithSyslogLevel(level))
+		}
+		return NewSyslogHook(opts.Network, opts.Addr, hookOpts...)
+	})
+}
+
+type SyslogFormat int
+
+const (
+	// RFC5424 is the default format, Fields are encoded as structured data.
+	RFC5424 SyslogFormat = iota + 1
+	// RFC3164 is the BSD format, Fields are appended to the message as key=value.
+	RFC3164
+)
+
+type Facility int
+
+const (
+	FacilityKern Facility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLocal0 Facility = iota + 10 // 16
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+var facilities = map[string]Facility{
+	"kern": FacilityKern, "user": FacilityUser, "mail": FacilityMail, "daemon": FacilityDaemon,
+	"auth": FacilityAuth, "syslog": FacilitySyslog,
+	"local0": FacilityLocal0, "local1": FacilityLocal1, "local2": FacilityLocal2, "local3": FacilityLocal3,
+	"local4": FacilityLocal4, "local5": FacilityLocal5, "local6": FacilityLocal6, "local7": FacilityLocal7,
+}
+
+// syslog severities
+const (
+	severityEmerg = iota
+	severityAlert
+	severityCrit
+	severityErr
+	severityWarning
+	severityNotice
+	severityInfo
+	severityDebug
+)
+
+// local sockets tried when the network is empty
+var localSyslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+type SyslogOption func(h *SyslogHook)
+
+// WithSyslogFormat sets the format, defaults to RFC5424, or RFC3164 for the local socket
+// which is what most syslog daemons expect on /dev/log.
+func WithSyslogFormat(format SyslogFormat) SyslogOption {
+	return func(h *SyslogHook) {
+		h.format = format
+	}
+}
+
+// WithSyslogFacility sets the facility, defaults to FacilityUser.
+func WithSyslogFacility(facility Facility) SyslogOption {
+	return func(h *SyslogHook) {
+		h.facility = facility
+	}
+}
+
+// WithSyslogLevel sends the entries at or above the level, defaults to InfoLevel.
+func WithSyslogLevel(level logx.Level) SyslogOption {
+	return func(h *SyslogHook) {
+		h.minLevel = level
+	}
+}
+
+func WithSyslogHostname(hostname string) SyslogOption {
+	return func(h *SyslogHook) {
+		h.hostname = hostname
+	}
+}
+
+// WithSyslogSDID sets the SD-ID of the structured data, defaults to "logx@32473".
+func WithSyslogSDID(id string) SyslogOption {
+	return func(h *SyslogHook) {
+		h.sdID = id
+	}
+}
+
+// WithSyslogBackoff sets the delay before redialing after a failure, it doubles from min up to max.
+// The entries wait in the queue meanwhile, Fire drops them with an error once it is full.
+func WithSyslogBackoff(min, max stdtime.Duration) SyslogOption {
+	return func(h *SyslogHook) {
+		h.minBackoff, h.maxBackoff = min, max
+	}
+}
+
+// WithSyslogTimeout sets the timeout of dialing and writing, defaults to 5 seconds.
+func WithSyslogTimeout(timeout stdtime.Duration) SyslogOption {
+	return func(h *SyslogHook) {
+		h.timeout = timeout
+	}
+}
+
+// WithSyslogQueueSize sets the number of entries waiting to be sent, defaults to 1000.
+func WithSyslogQueueSize(size int) SyslogOption {
+	return func(h *SyslogHook) {
+		h.queueSize = size
+	}
+}
+
+// SyslogHook sends the entries to a syslog server in a background goroutine. Fire never blocks,
+// an entry is dropped with an error if the queue is full. LogX.Name is the APP-NAME and LogX.Instance the PROCID.
+type SyslogHook struct {
+	network string
+	addr    string
+
+	format     SyslogFormat
+	facility   Facility
+	minLevel   logx.Level
+	levels     []logx.Level
+	hostname   string
+	sdID       string
+	timeout    stdtime.Duration
+	minBackoff stdtime.Duration
+	maxBackoff stdtime.Duration
+	queueSize  int
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan []byte
+	done   chan struct{}
+	// closing stops waiting for the reconnection
+	closing chan struct{}
+
+	// conn, backoff and nextDial are only accessed by the goroutine
+	conn     net.Conn
+	backoff  stdtime.Duration
+	nextDial stdtime.Time
+}
+
+// NewSyslogHook connects to the syslog server, network is one of udp, tcp, unix and unixgram.
+// An empty network and addr connect to the local socket, e.g. /dev/log.
+func NewSyslogHook(network, addr string, opts ...SyslogOption) (*SyslogHook, error) {
+	h := &SyslogHook{
+		network:    network,
+		addr:       addr,
+		facility:   FacilityUser,
+		minLevel:   logx.InfoLevel,
+		sdID:       "logx@32473",
+		timeout:    5 * stdtime.Second,
+		minBackoff: 100 * stdtime.Millisecond,
+		maxBackoff: 30 * stdtime.Second,
+		queueSize:  1000,
+	}
+	for _, o := range opts {
+		o(h)
+	}
+	h.levels = logx.LevelsFrom(h.minLevel)
+	if h.format == 0 {
+		h.format = RFC5424
+		if network == "" {
+			h.format = RFC3164
+		}
+	}
+	if h.hostname == "" {
+		h.hostname, _ = os.Hostname()
+	}
+
+	conn, err := h.dial()
+	if err != nil {
+		return nil, err
+	}
+	h.conn = conn
+	h.queue = make(chan []byte, h.queueSize)
+	h.done = make(chan struct{})
+	h.closing = make(chan struct{})
+	go h.run()
+	return h, nil
+}
+
+func (h *SyslogHook) dial() (net.Conn, error) {
+	if h.network != "" {
+		conn, err := net.DialTimeout(h.network, h.addr, h.timeout)
+		if err != nil {
+			return nil, fmt.Errorf("syslog: dial %s %s: %v", h.network, h.addr, err)
+		}
+		return conn, nil
+	}
+
+	for _, path := range localSyslogPaths {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.DialTimeout(network, path, h.timeout); err == nil {
+				return conn, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("syslog: no local syslog socket in %v", localSyslogPaths)
+}
+
+func (h *SyslogHook) SetLogger(*logx.LogX) {}
+
+func (h *SyslogHook) Levels() []logx.Level {
+	return h.levels
+}
+
+func (h *SyslogHook) Fire(entry *logx.Entry) error {
+	msg := h.message(entry)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		return fmt.Errorf("syslog: hook is closed")
+	}
+	select {
+	case h.queue <- msg:
+		return nil
+	default:
+		return fmt.Errorf("syslog: queue is full, entry dropped")
+	}
+}
+
+func (h *SyslogHook) run() {
+	defer close(h.done)
+	for msg := range h.queue {
+		if err := h.send(msg); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Failed to send to syslog, %v\n", err)
+		}
+	}
+}
+
+// send writes the message, a failed write may be caused by a stale connection,
+// so it is retried once on a new one. While the server is unreachable it redials after the backoff
+// until the hook is closed, the later entries stay queued in order.
+func (h *SyslogHook) send(msg []byte) error {
+	var err error
+	for i := 0; i < 2; i++ {
+		for h.conn == nil {
+			if err = h.connect(); err != nil && !h.waitDial() {
+				return err
+			}
+		}
+		if err = h.write(msg); err == nil {
+			return nil
+		}
+		_ = h.conn.Close()
+		h.conn = nil
+	}
+	return err
+}
+
+// connect redials if the connection is lost and the backoff has passed.
+func (h *SyslogHook) connect() error {
+	if h.conn != nil {
+		return nil
+	}
+	if now := stdtime.Now(); now.Before(h.nextDial) {
+		return fmt.Errorf("syslog: reconnecting in %v", h.nextDial.Sub(now).Round(stdtime.Millisecond))
+	}
+	conn, err := h.dial()
+	if err != nil {
+		if h.backoff == 0 {
+			h.backoff = h.minBackoff
+		} else if h.backoff *= 2; h.backoff > h.maxBackoff {
+			h.backoff = h.maxBackoff
+		}
+		h.nextDial = stdtime.Now().Add(h.backoff)
+		return err
+	}
+	h.conn, h.backoff = conn, 0
+	return nil
+}
+
+// waitDial waits until the backoff has passed, it returns false once the hook is closing.
+func (h *SyslogHook) waitDial() bool {
+	select {
+	case <-h.closing:
+		return false
+	default:
+	}
+	timer := stdtime.NewTimer(stdtime.Until(h.nextDial))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-h.closing:
+		return false
+	}
+}
+
+// write frames the message by the transport: octet counting on tcp, a newline on unix streams
+// and nothing on datagrams.
+func (h *SyslogHook) write(msg []byte) error {
+	var frame []byte
+	switch h.conn.LocalAddr().Network() {
+	case "tcp", "tcp4", "tcp6":
+		frame = append(strconv.AppendInt(nil, int64(len(msg)), 10), ' ')
+		frame = append(frame, msg...)
+	case "unix":
+		frame = append(msg, '\n')
+	default:
+		frame = msg
+	}
+	if h.timeout > 0 {
+		_ = h.conn.SetWriteDeadline(stdtime.Now().Add(h.timeout))
+	}
+	_, err := h.conn.Write(frame)
+	return err
+}
+
+// Close sends the queued entries and closes the connection, the entries fired later return an error.
+// It doesn't wait for the reconnection, the queued entries are dropped if the server is unreachable.
+func (h *SyslogHook) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	close(h.closing)
+	close(h.queue)
+	h.mu.Unlock()
+
+	<-h.done
+	if h.conn == nil {
+		return nil
+	}
+	err := h.conn.Close()
+	h.conn = nil
+	return err
+}
+
+func (h *SyslogHook) message(entry *logx.Entry) []byte {
+	var name, instance string
+	if entry.Logger != nil {
+		name, instance = entry.Logger.Name, entry.Logger.Instance
+	}
+	pri := int(h.facility)*8 + severity(entry.Level)
+	fields := entry.AllFields()
+
+	var buffer bytes.Buffer
+	if h.format == RFC3164 {
+		// <PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG, the local socket takes no hostname
+		fmt.Fprintf(&buffer, "<%d>%s ", pri, entry.Time.Format(stdtime.Stamp))
+		if h.network != "" {
+			buffer.WriteString(headerField(h.hostname, 255))
+			buffer.WriteByte(' ')
+		}
+		buffer.WriteString(headerField(name, 32))
+		if instance != "" {
+			buffer.WriteString("[" + instance + "]")
+		}
+		buffer.WriteString(": ")
+		buffer.WriteString(entry.Message)
+		for _, k := range sortedFieldKeys(fields) {
+			buffer.WriteString(" " + k + "=" + strconv.Quote(fieldString(fields[k])))
+		}
+		return buffer.Bytes()
+	}
+
+	// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	fmt.Fprintf(&buffer, "<%d>1 %s %s %s %s - ", pri, entry.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+		headerField(h.hostname, 255), headerField(name, 48), headerField(instance, 128))
+	if len(fields) == 0 {
+		buffer.WriteByte('-')
+	} else {
+		buffer.WriteString("[" + h.sdID)
+		for _, k := range sortedFieldKeys(fields) {
+			buffer.WriteString(" " + sdName(k) + `="`)
+			sdEscaper.WriteString(&buffer, fieldString(fields[k]))
+			buffer.WriteByte('"')
+		}
+		buffer.WriteByte(']')
+	}
+	if entry.Message != "" {
+		buffer.WriteByte(' ')
+		buffer.WriteString(entry.Message)
+	}
+	return buffer.Bytes()
+}
+
+// severity maps the level to syslog, custom levels between warn and info are notice.
+func severity(level logx.Level) int {
+	switch {
+	case level <= logx.PanicLevel:
+		return severityEmerg
+	case level <= logx.FatalLevel:
+		return severityCrit
+	case level <= logx.ErrorLevel:
+		return severityErr
+	case level <= logx.WarnLevel:
+		return severityWarning
+	case level < logx.InfoLevel:
+		return severityNotice
+	case level == logx.InfoLevel:
+		return severityInfo
+	}
+	return severityDebug
+}
+
+// headerField returns the printable ASCII of s up to max bytes, "-" if empty.
+func headerField(s string, max int) string {
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s) && len(b) < max; i++ {
+		if c := s[i]; c > ' ' && c < 0x7f {
+			b = append(b, c)
+		}
+	}
+	if len(b) == 0 {
+		return "-"
+	}
+	return string(b)
+}
+
+// sdName replaces the characters not allowed in a PARAM-NAME with '_', it is up to 32 bytes.
+func sdName(key string) string {
+	b := make([]byte, 0, len(key))
+	for i := 0; i < len(key) && len(b) < 32; i++ {
+		c := key[i]
+		if c <= ' ' || c >= 0x7f || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	if len(b) == 0 {
+		return "_"
+	}
+	return string(b)
+}
+
+// sdEscaper escapes a PARAM-VALUE
+var sdEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`, `]`, `\]`)
+
+func fieldString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case error:
+		return val.Error()
+	case stdtime.Time:
+		return val.Format(stdtime.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+func sortedFieldKeys(fields logx.Fields) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,211 @@
+package hooks
+
+import (
+	"bufio"
+	"github.com/xiaorui77/goutils/logx"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSyslogLogger(hook *SyslogHook) *logx.LogX {
+	return logx.NewLogx("app", logx.WithInstance("app-1"), logx.WithOutput(nil), logx.WithoutEnv(), logx.WithHook(hook))
+}
+
+func TestSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %v", err)
+	}
+	defer conn.Close()
+
+	hook, err := NewSyslogHook("udp", conn.LocalAddr().String(), WithSyslogHostname("host"),
+		WithSyslogFacility(FacilityLocal0))
+	if err != nil {
+		t.Fatalf("NewSyslogHook() error: %v", err)
+	}
+	defer hook.Close()
+	logger := newSyslogLogger(hook)
+
+	logger.Debug("dropped")
+	logger.WithField("path", `/a"b]`).Warnw("slow request", logx.Int("cost", 3))
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	msg := string(buf[:n])
+	// local0 * 8 + warning
+	if !strings.HasPrefix(msg, "<132>1 ") {
+		t.Errorf("unexpected header %q", msg)
+	}
+	expected := ` host app app-1 - [logx@32473 cost="3" path="/a\"b\]"] slow request`
+	if !strings.HasSuffix(msg, expected) {
+		t.Errorf("expected suffix %q, actual %q", expected, msg)
+	}
+}
+
+func TestSyslogTCPReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %v", err)
+	}
+	defer ln.Close()
+
+	messages := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// read one octet-counted frame and drop the connection
+			r := bufio.NewReader(conn)
+			size, err := r.ReadString(' ')
+			if err == nil {
+				n, _ := strconv.Atoi(strings.TrimSpace(size))
+				buf := make([]byte, n)
+				if _, err := io.ReadFull(r, buf); err == nil {
+					messages <- string(buf)
+				}
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	hook, err := NewSyslogHook("tcp", ln.Addr().String(), WithSyslogBackoff(time.Millisecond, 10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewSyslogHook() error: %v", err)
+	}
+	defer hook.Close()
+	logger := newSyslogLogger(hook)
+	logger.SetHookErrorHandler(func(logx.Hook, *logx.Entry, error) {})
+
+	logger.Error("first")
+	if msg := <-messages; !strings.HasPrefix(msg, "<11>1 ") || !strings.HasSuffix(msg, " - first") {
+		t.Errorf("unexpected message %q", msg)
+	}
+
+	// the server closed the connection, the hook reconnects
+	deadline := time.After(5 * time.Second)
+	for {
+		logger.Error("second")
+		select {
+		case msg := <-messages:
+			if !strings.HasSuffix(msg, " - second") {
+				t.Errorf("unexpected message %q", msg)
+			}
+			return
+		case <-deadline:
+			t.Fatalf("expected the hook reconnected")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+func TestSyslogBackoffKeepsEntries(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %v", err)
+	}
+	defer conn.Close()
+
+	hook, err := NewSyslogHook("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("NewSyslogHook() error: %v", err)
+	}
+	defer hook.Close()
+	// the connection is lost and the backoff is active, the first Fire publishes the change to the goroutine
+	_ = hook.conn.Close()
+	hook.conn, hook.backoff, hook.nextDial = nil, hook.minBackoff, time.Now().Add(100*time.Millisecond)
+
+	logger := newSyslogLogger(hook)
+	for _, msg := range []string{"first", "second", "third"} {
+		logger.Info(msg)
+	}
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for _, expected := range []string{"first", "second", "third"} {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("read error: %v", err)
+		}
+		if msg := string(buf[:n]); !strings.HasSuffix(msg, " - "+expected) {
+			t.Errorf("expected %s sent after the backoff, actual %q", expected, msg)
+		}
+	}
+}
+
+func TestSyslogStalledServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %v", err)
+	}
+	defer ln.Close()
+	// accept the connections and never read
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	hook, err := NewSyslogHook("tcp", ln.Addr().String(), WithSyslogQueueSize(1), WithSyslogTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewSyslogHook() error: %v", err)
+	}
+	entry := &logx.Entry{Logger: logx.NewLogx("app", logx.WithoutEnv()), Level: logx.InfoLevel,
+		Message: strings.Repeat("x", 64<<10)}
+
+	start := time.Now()
+	var dropped int
+	for i := 0; i < 1000; i++ {
+		if err := hook.Fire(entry); err != nil {
+			dropped++
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second || dropped == 0 {
+		t.Errorf("expected Fire() not blocked by the server, %d dropped in %v", dropped, elapsed)
+	}
+	_ = hook.Close()
+}
+
+func TestSyslogLocalSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram is not supported: %v", err)
+	}
+	defer conn.Close()
+
+	defer func(paths []string) { localSyslogPaths = paths }(localSyslogPaths)
+	localSyslogPaths = []string{filepath.Join(t.TempDir(), "missing"), path}
+
+	hook, err := NewSyslogHook("", "")
+	if err != nil {
+		t.Fatalf("NewSyslogHook() error: %v", err)
+	}
+	defer hook.Close()
+	newSyslogLogger(hook).WithField("user", "tom").Info("login")
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	// RFC 3164 without hostname, user * 8 + info
+	msg := string(buf[:n])
+	if !strings.HasPrefix(msg, "<14>") || !strings.HasSuffix(msg, ` app[app-1]: login user="tom"`) {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
@@ -0,0 +1,198 @@
+package logx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sync"
+	"sync/atomic"
+)
+
+// moduleLevels holds the level overrides by logger name pattern, it is shared by a logger and its children.
+type moduleLevels struct {
+	mu       sync.RWMutex
+	patterns map[string]Level
+
+	// generation is increased on every change to invalidate the caches of the loggers
+	generation uint32
+}
+
+func newModuleLevels() *moduleLevels {
+	return &moduleLevels{patterns: map[string]Level{}, generation: 1}
+}
+
+func (m *moduleLevels) replace(patterns map[string]Level) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.patterns = patterns
+	atomic.AddUint32(&m.generation, 1)
+}
+
+// resolve returns the level of the most specific pattern matching name:
+// an exact name wins, otherwise the longest matching pattern.
+func (m *moduleLevels) resolve(name string) (Level, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if level, ok := m.patterns[name]; ok {
+		return level, true
+	}
+	var matched string
+	var level Level
+	found := false
+	for pattern, lvl := range m.patterns {
+		if ok, _ := path.Match(pattern, name); ok && (!found || len(pattern) > len(matched)) {
+			matched, level, found = pattern, lvl, true
+		}
+	}
+	return level, found
+}
+
+// moduleLevel returns the override of the logger name, the result is cached until the overrides change.
+func (l *LogX) moduleLevel() (Level, bool) {
+	m := l.modules
+	if m == nil {
+		return 0, false
+	}
+	gen := atomic.LoadUint32(&m.generation)
+	cache := atomic.LoadUint64(&l.moduleCache)
+	if uint32(cache>>32) == gen {
+		v := uint32(cache)
+		return Level(int32(v) - 1), v != 0
+	}
+
+	level, ok := m.resolve(l.Name)
+	var v uint32
+	if ok {
+		v = uint32(int32(level) + 1)
+	}
+	atomic.StoreUint64(&l.moduleCache, uint64(gen)<<32|uint64(v))
+	return level, ok
+}
+
+// SetModuleLevel overrides the level of the loggers whose name matches the pattern, e.g. "app.db.*".
+// The pattern syntax is the same as path.Match, the override is shared by the whole logger tree.
+func (l *LogX) SetModuleLevel(pattern string, level Level) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("logx: invalid module pattern %q: %v", pattern, err)
+	}
+	m := l.modules
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.patterns[pattern] = level
+	atomic.AddUint32(&m.generation, 1)
+	return nil
+}
+
+func (l *LogX) RemoveModuleLevel(pattern string) {
+	m := l.modules
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.patterns, pattern)
+	atomic.AddUint32(&m.generation, 1)
+}
+
+// ModuleLevels returns a copy of the level overrides.
+func (l *LogX) ModuleLevels() map[string]Level {
+	m := l.modules
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	levels := make(map[string]Level, len(m.patterns))
+	for k, v := range m.patterns {
+		levels[k] = v
+	}
+	return levels
+}
+
+// LevelHandler returns an http.Handler to view and change the levels at runtime:
+//
+//	GET  returns {"level":"info","modules":{"app.db.*":"debug"}}
+//	PUT  accepts the same body, both keys are optional, a module with level "" is removed.
+//
+// It can be mounted on httpr, e.g. engine.Handle(http.MethodPut, "/debug/level", logx.LevelHandler(logger)).
+func LevelHandler(l *LogX) http.Handler {
+	return &levelHandler{logger: l}
+}
+
+type levelHandler struct {
+	logger *LogX
+}
+
+type levelState struct {
+	Level   string            `json:"level,omitempty"`
+	Modules map[string]string `json:"modules,omitempty"`
+}
+
+func (h *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		if err := h.update(r); err != nil {
+			h.writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		h.writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only GET and PUT are supported"})
+		return
+	}
+	h.writeJSON(w, http.StatusOK, h.state())
+}
+
+// update validates the whole request before applying, so an invalid request changes nothing.
+func (h *levelHandler) update(r *http.Request) error {
+	var req levelState
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+
+	var level Level
+	if req.Level != "" {
+		lvl, ok := LookupLevel(req.Level)
+		if !ok {
+			return fmt.Errorf("unknown level %q", req.Level)
+		}
+		level = lvl
+	}
+	modules := make(map[string]Level, len(req.Modules))
+	for pattern, name := range req.Modules {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid module pattern %q: %v", pattern, err)
+		}
+		if name == "" {
+			continue
+		}
+		lvl, ok := LookupLevel(name)
+		if !ok {
+			return fmt.Errorf("unknown level %q of module %q", name, pattern)
+		}
+		modules[pattern] = lvl
+	}
+
+	if req.Level != "" {
+		h.logger.SetLevel(level)
+	}
+	for pattern, name := range req.Modules {
+		if name == "" {
+			h.logger.RemoveModuleLevel(pattern)
+		} else {
+			_ = h.logger.SetModuleLevel(pattern, modules[pattern])
+		}
+	}
+	return nil
+}
+
+func (h *levelHandler) state() levelState {
+	state := levelState{Level: h.logger.GetLevel().String(), Modules: map[string]string{}}
+	for pattern, level := range h.logger.ModuleLevels() {
+		state.Modules[pattern] = level.String()
+	}
+	return state
+}
+
+func (h *levelHandler) writeJSON(w http.ResponseWriter, status int, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(obj)
+}
@@ -0,0 +1,141 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestModuleLevel(t *testing.T) {
+	root := NewLogx("app")
+	db := root.Named("db")
+	pool := db.Named("pool")
+	web := root.Named("http")
+
+	if err := root.SetModuleLevel("app.db.*", DebugLevel); err != nil {
+		t.Fatalf("SetModuleLevel() error: %v", err)
+	}
+	_ = root.SetModuleLevel("app.db.pool", ErrorLevel)
+	_ = root.SetModuleLevel("app.*", WarnLevel)
+
+	tests := []struct {
+		logger   *LogX
+		expected Level
+	}{
+		{root, InfoLevel},
+		{db, WarnLevel},
+		{pool, ErrorLevel},
+		{pool.Named("conn"), DebugLevel},
+		{web, WarnLevel},
+	}
+	for _, test := range tests {
+		if actual := test.logger.GetLevel(); actual != test.expected {
+			t.Errorf("logger %s: expected %v, actual %v", test.logger.Name, test.expected, actual)
+		}
+	}
+
+	root.RemoveModuleLevel("app.*")
+	if actual := web.GetLevel(); actual != InfoLevel {
+		t.Errorf("expected level of parent after removing the override, actual %v", actual)
+	}
+	if err := root.SetModuleLevel("app.[", DebugLevel); err == nil {
+		t.Errorf("expected error of invalid pattern")
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	logger := NewLogx("app")
+	handler := LevelHandler(logger)
+
+	req := httptest.NewRequest(http.MethodPut, "/level",
+		strings.NewReader(`{"level":"warn","modules":{"app.db.*":"debug"}}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, actual %d: %s", w.Code, w.Body)
+	}
+	if logger.GetLevel() != WarnLevel || logger.Named("db").Named("x").GetLevel() != DebugLevel {
+		t.Errorf("expected levels applied immediately")
+	}
+
+	// invalid request changes nothing
+	req = httptest.NewRequest(http.MethodPut, "/level",
+		strings.NewReader(`{"level":"info","modules":{"app.http":"verbose"}}`))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || logger.GetLevel() != WarnLevel {
+		t.Errorf("expected rejected request, status %d level %v", w.Code, logger.GetLevel())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/level", nil))
+	var state struct {
+		Level   string            `json:"level"`
+		Modules map[string]string `json:"modules"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &state)
+	if state.Level != "warn" || state.Modules["app.db.*"] != "debug" {
+		t.Errorf("unexpected state %s", w.Body)
+	}
+}
+
+func TestTraceLevel(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := NewLogx("test", WithOutput(&buffer), WithFormatter(NewTextFormatter(nil, false)), WithLevel(DebugLevel))
+
+	logger.Trace("wire")
+	logger.Debug("debug")
+	if strings.Contains(buffer.String(), "wire") || !strings.Contains(buffer.String(), "DEBUG - debug") {
+		t.Errorf("expected trace dropped at debug level, actual %q", buffer.String())
+	}
+
+	logger.SetLevel(ParseLevel("TRACE"))
+	logger.Tracew("wire", Int("bytes", 3))
+	if !strings.Contains(buffer.String(), "TRACE - wire bytes=3") {
+		t.Errorf("expected trace logged, actual %q", buffer.String())
+	}
+}
+
+func TestRegisterLevel(t *testing.T) {
+	const noticeLevel Level = 35
+	if err := RegisterLevel(noticeLevel, "Notice", "NOTE", cyan); err != nil {
+		t.Fatalf("RegisterLevel() error: %v", err)
+	}
+	t.Cleanup(func() { unregisterLevel(noticeLevel) })
+	if err := RegisterLevel(noticeLevel, "other", "", cyan); err == nil {
+		t.Errorf("expected error on a registered level")
+	}
+	if err := RegisterLevel(36, "info", "", cyan); err == nil {
+		t.Errorf("expected error on a registered name")
+	}
+
+	if level, ok := LookupLevel("NOTICE"); !ok || level != noticeLevel || level.String() != "notice" {
+		t.Errorf("expected notice looked up, actual %v %v", level, ok)
+	}
+	levels := LevelsFrom(InfoLevel)
+	if len(levels) != 6 || levels[3] != WarnLevel || levels[4] != noticeLevel || levels[5] != InfoLevel {
+		t.Errorf("expected notice between warn and info, actual %v", levels)
+	}
+
+	var buffer bytes.Buffer
+	capture := &captureHook{}
+	logger := NewLogx("test", WithOutput(&buffer), WithFormatter(NewTextFormatter(nil, false)),
+		WithLevel(WarnLevel), WithHook(capture))
+	logger.Log(1, noticeLevel, "dropped")
+	logger.SetLevel(InfoLevel)
+	logger.Log(1, noticeLevel, "audit")
+	if !strings.Contains(buffer.String(), " NOTE - audit") || strings.Contains(buffer.String(), "dropped") {
+		t.Errorf("expected notice logged at info level, actual %q", buffer.String())
+	}
+	if messages := capture.messages(); len(messages) != 1 || messages[0] != "audit" {
+		t.Errorf("expected notice fired to hooks, actual %v", messages)
+	}
+
+	data, _ := NewJSONFormatter().Format(&Entry{Level: noticeLevel, Message: "audit"})
+	if !strings.Contains(string(data), `"level":"notice"`) {
+		t.Errorf("expected json level notice, actual %s", data)
+	}
+}
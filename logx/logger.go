@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,36 +22,141 @@ func (l *LogX) WithFields(fields Fields) *Entry {
 
 // useful methods
 
+// SetLevel is safe to call while logging, on a child logger it overrides the inherited level.
 func (l *LogX) SetLevel(level Level) {
-	l.level = level
+	atomic.StoreInt32(&l.level, int32(level))
+	atomic.StoreInt32(&l.levelSet, 1)
 }
 
+// SetReportCaller is safe to call while logging.
 func (l *LogX) SetReportCaller(reportCaller bool) {
-	l.ReportCaller = reportCaller
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.updateSettings(func(s *settings) { s.reportCaller = reportCaller })
 }
 
+// SetOutput is safe to call while logging, on a child logger nil means the output of the parent.
 func (l *LogX) SetOutput(out io.Writer) {
-	l.Out = out
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.updateSettings(func(s *settings) { s.out = out })
+}
+
+// SetFormatter is safe to call while logging, on a child logger nil means the formatter of the parent.
+func (l *LogX) SetFormatter(formatter Formatter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.updateSettings(func(s *settings) { s.formatter = formatter })
+}
+
+func (l *LogX) GetReportCaller() bool {
+	return l.loadSettings().reportCaller
+}
+
+// GetOutput returns the output of the logger, a child logger returns the one inherited from its parent.
+func (l *LogX) GetOutput() io.Writer {
+	return l.output()
+}
+
+// GetFormatter returns the formatter of the logger, a child logger returns the one inherited from its parent.
+func (l *LogX) GetFormatter() Formatter {
+	return l.formatter()
+}
+
+// Flush writes the held duplicates, waits for the async hooks
+// and writes the buffered entries if the output supports it, e.g. AsyncWriter.
+func (l *LogX) Flush() error {
+	if l.deduper != nil {
+		l.deduper.flush()
+	}
+	l.flushHooks()
+	for _, w := range l.writers() {
+		if f, ok := w.(interface{ Flush() error }); ok {
+			if err := f.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close stops sampling, flushes and closes the output and the writers of the sinks,
+// os.Stdout and os.Stderr are never closed.
+// The output shared by a child logger is closed by its owner only.
+func (l *LogX) Close() error {
+	if s := l.sampler; s != nil && s.owner == l {
+		s.stop()
+	}
+	if err := l.Flush(); err != nil {
+		return err
+	}
+	writers := []io.Writer{l.loadSettings().out}
+	for _, s := range l.loadSinks() {
+		writers = append(writers, s.Out)
+	}
+	var err error
+	for _, w := range writers {
+		if w == os.Stdout || w == os.Stderr {
+			continue
+		}
+		if c, ok := w.(io.Closer); ok {
+			if closeErr := c.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}
+	}
+	return err
 }
 
 // inner methods
 
 func (l *LogX) IsLevelEnabled(level Level) bool {
-	return l.level >= level
+	return l.GetLevel() >= level
+}
+
+// SetExitFunc replaces os.Exit called by the Fatal family functions of LogX, e.g. to test them.
+// The Fatal functions of Entry don't exit.
+func (l *LogX) SetExitFunc(exit func(code int)) {
+	l.exitFunc.Store(exit)
+}
+
+func WithExitFunc(exit func(code int)) Option {
+	return func(l *LogX) {
+		l.SetExitFunc(exit)
+	}
+}
+
+// Exit calls the exit func of the logger or the nearest ancestor which has one, defaults to os.Exit.
+func (l *LogX) Exit(code int) {
+	for logger := l; logger != nil; logger = logger.parent {
+		if exit, ok := logger.exitFunc.Load().(func(int)); ok && exit != nil {
+			exit(code)
+			return
+		}
+	}
+	os.Exit(code)
 }
 
 func (l *LogX) getEntry() *Entry {
 	entry, ok := l.entryPool.Get().(*Entry)
 	if ok {
+		// Fields is shared read-only, WithFields always copies into a new map
 		entry.Time = time.Now()
-		entry.Fields = make(Fields, 4)
+		entry.Fields = l.fields
 		return entry
 	}
-	return NewEntry(l)
+	entry = NewEntry(l)
+	entry.Fields = l.fields
+	return entry
 }
 
 func (l *LogX) releaseEntry(entry *Entry) {
 	entry.Fields = nil
+	entry.TypedFields = nil
+	entry.Stack = nil
+	entry.Err = nil
+	entry.settingsLoaded = false
+	entry.settings = settings{}
 	l.entryPool.Put(entry)
 }
 
@@ -64,6 +170,10 @@ func (l *LogX) Log(depth int, level Level, args ...interface{}) {
 	}
 }
 
+func (l *LogX) Trace(args ...interface{}) {
+	l.Log(2, TraceLevel, args...)
+}
+
 func (l *LogX) Debug(args ...interface{}) {
 	l.Log(2, DebugLevel, args...)
 }
@@ -82,7 +192,7 @@ func (l *LogX) Error(args ...interface{}) {
 
 func (l *LogX) Fatal(args ...interface{}) {
 	l.Log(2, FatalLevel, args...)
-	os.Exit(1)
+	l.Exit(1)
 }
 
 func (l *LogX) Panic(args ...interface{}) {
@@ -100,6 +210,10 @@ func (l *LogX) Logf(depth int, level Level, format string, args ...interface{})
 	}
 }
 
+func (l *LogX) Tracef(format string, args ...interface{}) {
+	l.Logf(2, TraceLevel, format, args...)
+}
+
 func (l *LogX) Debugf(format string, args ...interface{}) {
 	l.Logf(2, DebugLevel, format, args...)
 }
@@ -118,10 +232,50 @@ func (l *LogX) Errorf(format string, args ...interface{}) {
 
 func (l *LogX) Fatalf(format string, args ...interface{}) {
 	l.Logf(2, FatalLevel, format, args...)
-	os.Exit(1)
+	l.Exit(1)
 }
 
 func (l *LogX) Panicf(format string, args ...interface{}) {
 	l.Logf(2, PanicLevel, format, args...)
 	panic(fmt.Sprintf(format, args...))
 }
+
+// Printw family functions, the typed fields are encoded without boxing
+
+func (l *LogX) Logw(depth int, level Level, msg string, fields ...Field) {
+	if l.IsLevelEnabled(level) {
+		entry := l.getEntry()
+		entry.Logw(depth+1, level, msg, fields...)
+		l.releaseEntry(entry)
+	}
+}
+
+func (l *LogX) Tracew(msg string, fields ...Field) {
+	l.Logw(2, TraceLevel, msg, fields...)
+}
+
+func (l *LogX) Debugw(msg string, fields ...Field) {
+	l.Logw(2, DebugLevel, msg, fields...)
+}
+
+func (l *LogX) Infow(msg string, fields ...Field) {
+	l.Logw(2, InfoLevel, msg, fields...)
+}
+
+func (l *LogX) Warnw(msg string, fields ...Field) {
+	l.Logw(2, WarnLevel, msg, fields...)
+}
+
+func (l *LogX) Errorw(msg string, fields ...Field) {
+	l.Logw(2, ErrorLevel, msg, fields...)
+}
+
+func (l *LogX) Fatalw(msg string, fields ...Field) {
+	l.Logw(2, FatalLevel, msg, fields...)
+	l.Exit(1)
+}
+
+func (l *LogX) Panicw(msg string, fields ...Field) {
+	l.Logw(2, PanicLevel, msg, fields...)
+	panic(msg)
+}
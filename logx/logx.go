@@ -5,38 +5,65 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 var std = NewLogx("std")
 var once sync.Once
 
 type LogX struct {
+	// moduleCache is accessed atomically, keep it first for 64-bit alignment
+	moduleCache uint64
+
 	Name     string
 	Instance string
 
-	level        Level
-	ReportCaller bool
-
-	Formatter Formatter
-	Out       io.Writer
-	mu        *sync.Mutex
-	hooks     map[Level][]Hook
+	// level, levelSet and stacktrace are accessed atomically
+	level      int32
+	levelSet   int32
+	stacktrace int32
+	modules    *moduleLevels
+
+	// settings holds the *settings of the formatter, output and caller reporting,
+	// see SetFormatter, SetOutput and SetReportCaller
+	settings atomic.Value
+	// sinks holds []*Sink, it is replaced on change like hooks
+	sinks atomic.Value
+	// mu guards the changes of the settings and the writes, generation counts the changes,
+	// both are shared with the children
+	mu         *sync.Mutex
+	generation *uint64
+
+	// hooks holds []*registeredHook, it is replaced on change so logging reads it without the lock
+	hooks        atomic.Value
+	errorHandler atomic.Value
+	// exitFunc holds the func(int) called by the Fatal family functions
+	exitFunc atomic.Value
+
+	sampler *sampler
+	deduper *deduper
+
+	// parent is set for the loggers created by Named and With
+	parent *LogX
+	fields Fields
+
+	// config is set by ApplyConfig
+	config *configState
+	noEnv  bool
 
 	entryPool *sync.Pool
 }
 
 func NewLogx(name string, opts ...Option) *LogX {
 	logger := &LogX{
-		Name:         name,
-		Instance:     name + "-0",
-		level:        InfoLevel,
-		ReportCaller: false,
-		Out:          os.Stdout,
-		mu:           new(sync.Mutex),
-		hooks:        make(map[Level][]Hook),
+		Name:       name,
+		Instance:   name + "-0",
+		level:      int32(InfoLevel),
+		modules:    newModuleLevels(),
+		mu:         new(sync.Mutex),
+		generation: new(uint64),
 	}
-
-	logger.Formatter = NewTextFormatter(logger, true)
+	logger.settings.Store(&settings{formatter: NewTextFormatter(logger, true), out: os.Stdout})
 	logger.entryPool = &sync.Pool{
 		New: func() interface{} {
 			return NewEntry(logger)
@@ -50,10 +77,11 @@ func NewLogx(name string, opts ...Option) *LogX {
 	return logger
 }
 
+// Init creates the std logger, the LOGX_* environment variables are applied after opts unless WithoutEnv is given.
 func Init(name string, opts ...Option) {
 	once.Do(func() {
 		if std == nil || std.Name == "std" {
-			std = NewLogx(name, opts...)
+			std = NewLogx(name, append(opts[:len(opts):len(opts)], WithEnv())...)
 		}
 	})
 }
@@ -86,6 +114,20 @@ func WithOutput(out io.Writer) Option {
 	}
 }
 
+// WithAsync writes entries by an AsyncWriter wrapping the current output,
+// so it should be placed after WithOutput.
+func WithAsync(size int, policy OverflowPolicy) Option {
+	return func(l *LogX) {
+		l.SetOutput(NewAsyncWriter(l.GetOutput(), size, policy))
+	}
+}
+
+func WithFormatter(formatter Formatter) Option {
+	return func(l *LogX) {
+		l.SetFormatter(formatter)
+	}
+}
+
 func WithHook(hook Hook) Option {
 	return func(l *LogX) {
 		l.AddHook(hook)
@@ -94,20 +136,19 @@ func WithHook(hook Hook) Option {
 
 // Utils functions
 
+// ParseLevel returns InfoLevel for unknown names, use LookupLevel to detect them.
 func ParseLevel(lvl string) Level {
-	switch strings.ToLower(lvl) {
-	case "panic":
-		return PanicLevel
-	case "fatal":
-		return FatalLevel
-	case "error":
-		return ErrorLevel
-	case "warn", "warning":
-		return WarnLevel
-	case "info":
-		return InfoLevel
-	case "debug":
-		return DebugLevel
+	if level, ok := LookupLevel(lvl); ok {
+		return level
 	}
 	return InfoLevel
 }
+
+// LookupLevel returns the level by name case-insensitively, ok is false if the name is unknown.
+// Custom levels are looked up as well, see RegisterLevel.
+func LookupLevel(lvl string) (Level, bool) {
+	if level, ok := loadLevels().names[strings.ToLower(lvl)]; ok {
+		return level, true
+	}
+	return InfoLevel, false
+}
@@ -0,0 +1,265 @@
+// Package logxtest records the entries of logx loggers in memory and asserts on them in unit tests, e.g.
+//
+//	logger, logs := logxtest.NewObserved()
+//	NewServer(logger).Start()
+//	logs.AssertLogged(t, logx.ErrorLevel, "listen failed", logx.Fields{"port": 8080})
+package logxtest
+
+import (
+	"context"
+	"fmt"
+	"github.com/xiaorui77/goutils/logx"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// LoggedEntry is a copy of a logx.Entry which is kept after logging.
+type LoggedEntry struct {
+	Time    time.Time
+	Name    string
+	Level   logx.Level
+	Message string
+	// Fields holds Fields and TypedFields of the entry, the message of Err is added as "error".
+	Fields  logx.Fields
+	Err     error
+	Caller  *runtime.Frame
+	Context context.Context
+}
+
+// Observer is a hook which records the entries of every level.
+type Observer struct {
+	mu      sync.Mutex
+	entries []LoggedEntry
+}
+
+func NewObserver() *Observer {
+	return &Observer{}
+}
+
+func (o *Observer) SetLogger(*logx.LogX) {}
+
+func (o *Observer) Levels() []logx.Level {
+	return logx.AllLevels()
+}
+
+func (o *Observer) Fire(entry *logx.Entry) error {
+	e := LoggedEntry{
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Message: entry.Message,
+		Fields:  logx.Fields{},
+		Err:     entry.Err,
+		Context: entry.Context,
+	}
+	if entry.Logger != nil {
+		e.Name = entry.Logger.Name
+	}
+	for k, v := range entry.AllFields() {
+		e.Fields[k] = v
+	}
+	if entry.Caller != nil {
+		caller := *entry.Caller
+		e.Caller = &caller
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.entries = append(o.entries, e)
+	return nil
+}
+
+// NewObserved returns a logger which records all levels with the caller and writes nothing.
+func NewObserved(opts ...logx.Option) (*logx.LogX, *Observer) {
+	o := NewObserver()
+	opts = append([]logx.Option{logx.WithoutEnv(), logx.WithOutput(nil), logx.WithLevel(logx.TraceLevel),
+		logx.WithReportCaller(true)}, opts...)
+	logger := logx.NewLogx("test", append(opts, logx.WithHook(o))...)
+	return logger, o
+}
+
+// Observe records the entries of the logger until the test finishes.
+func Observe(t testing.TB, logger *logx.LogX) *Observer {
+	o := NewObserver()
+	logger.AddHook(o)
+	t.Cleanup(func() { logger.RemoveHook(o) })
+	return o
+}
+
+// ObserveStd records the entries of the std logger until the test finishes, call it after logx.Init.
+func ObserveStd(t testing.TB) *Observer {
+	return Observe(t, logx.Std())
+}
+
+// All returns a copy of the recorded entries.
+func (o *Observer) All() Entries {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append(Entries(nil), o.entries...)
+}
+
+// TakeAll returns the recorded entries and resets the observer.
+func (o *Observer) TakeAll() Entries {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entries := o.entries
+	o.entries = nil
+	return entries
+}
+
+func (o *Observer) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.entries)
+}
+
+func (o *Observer) Reset() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.entries = nil
+}
+
+// AssertLogged fails the test unless an entry of the level contains msgSubstring and has the fields,
+// see Entries.FilterFields for how the values are compared.
+func (o *Observer) AssertLogged(t testing.TB, level logx.Level, msgSubstring string, fields logx.Fields) {
+	t.Helper()
+	all := o.All()
+	if len(all.FilterLevel(level).FilterMessage(msgSubstring).FilterFields(fields)) == 0 {
+		t.Errorf("expected a %s entry containing %q with fields %v, logged:\n%s", level, msgSubstring, fields, all)
+	}
+}
+
+// AssertNotLogged fails the test if an entry of the level contains msgSubstring.
+func (o *Observer) AssertNotLogged(t testing.TB, level logx.Level, msgSubstring string) {
+	t.Helper()
+	if matched := o.All().FilterLevel(level).FilterMessage(msgSubstring); len(matched) > 0 {
+		t.Errorf("expected no %s entry containing %q, logged:\n%s", level, msgSubstring, matched)
+	}
+}
+
+// Entries is a list of recorded entries, the Filter methods return new lists.
+type Entries []LoggedEntry
+
+func (es Entries) Filter(keep func(e LoggedEntry) bool) Entries {
+	var filtered Entries
+	for _, e := range es {
+		if keep(e) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+func (es Entries) FilterLevel(level logx.Level) Entries {
+	return es.Filter(func(e LoggedEntry) bool { return e.Level == level })
+}
+
+// FilterMessage keeps the entries whose message contains the substring.
+func (es Entries) FilterMessage(substring string) Entries {
+	return es.Filter(func(e LoggedEntry) bool { return strings.Contains(e.Message, substring) })
+}
+
+// FilterFields keeps the entries which have all the fields. Values are equal if they are deeply equal
+// or print the same, so logx.Int("n", 1) matches logx.Fields{"n": 1}.
+func (es Entries) FilterFields(fields logx.Fields) Entries {
+	return es.Filter(func(e LoggedEntry) bool {
+		for k, expected := range fields {
+			actual, ok := e.Fields[k]
+			if !ok || !valueEqual(expected, actual) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func (es Entries) Messages() []string {
+	messages := make([]string, len(es))
+	for i, e := range es {
+		messages[i] = e.Message
+	}
+	return messages
+}
+
+func (es Entries) String() string {
+	var b strings.Builder
+	for _, e := range es {
+		_, _ = fmt.Fprintf(&b, "\t%s %q %v\n", e.Level, e.Message, e.Fields)
+	}
+	return b.String()
+}
+
+func valueEqual(expected, actual interface{}) bool {
+	return reflect.DeepEqual(expected, actual) || fmt.Sprint(expected) == fmt.Sprint(actual)
+}
+
+// ExitStub records the calls to the exit func of a logger instead of exiting,
+// the code after a Fatal call keeps running.
+type ExitStub struct {
+	mu    sync.Mutex
+	codes []int
+}
+
+// StubExit replaces the exit func of the logger until the test finishes.
+func StubExit(t testing.TB, logger *logx.LogX) *ExitStub {
+	s := &ExitStub{}
+	logger.SetExitFunc(s.exit)
+	t.Cleanup(func() { logger.SetExitFunc(nil) })
+	return s
+}
+
+func (s *ExitStub) exit(code int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes = append(s.codes, code)
+}
+
+func (s *ExitStub) Exited() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.codes) > 0
+}
+
+// Code returns the code of the first exit, or -1 if it was not called.
+func (s *ExitStub) Code() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.codes) == 0 {
+		return -1
+	}
+	return s.codes[0]
+}
+
+// NewLogger returns a logger which writes uncolored text to t.Log, so the logs are shown
+// under the test which wrote them. Entries logged after the test finishes are dropped.
+func NewLogger(t testing.TB, opts ...logx.Option) *logx.LogX {
+	w := &testWriter{t: t}
+	t.Cleanup(w.close)
+	opts = append([]logx.Option{logx.WithoutEnv(), logx.WithOutput(w), logx.WithLevel(logx.TraceLevel),
+		logx.WithFormatter(logx.NewTextFormatter(nil, false))}, opts...)
+	return logx.NewLogx(t.Name(), opts...)
+}
+
+type testWriter struct {
+	mu   sync.Mutex
+	t    testing.TB
+	done bool
+}
+
+func (w *testWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.done {
+		w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	}
+	return len(p), nil
+}
+
+func (w *testWriter) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.done = true
+}
@@ -0,0 +1,92 @@
+package logxtest
+
+import (
+	"errors"
+	"github.com/xiaorui77/goutils/logx"
+	"strings"
+	"testing"
+)
+
+// fakeT records the failures of the assertions.
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, format)
+}
+
+func TestObserver(t *testing.T) {
+	logger, logs := NewObserved()
+
+	logger.WithField("user", "tom").Info("login ok")
+	logger.Named("db").WithError(errors.New("timeout")).Errorw("query failed", logx.Int("retry", 3))
+
+	if logs.Len() != 2 {
+		t.Fatalf("expected 2 entries, actual %d", logs.Len())
+	}
+	logs.AssertLogged(t, logx.InfoLevel, "login", logx.Fields{"user": "tom"})
+	logs.AssertLogged(t, logx.ErrorLevel, "query", logx.Fields{"retry": 3, "error": "timeout"})
+	logs.AssertNotLogged(t, logx.WarnLevel, "")
+
+	entry := logs.All().FilterLevel(logx.ErrorLevel)[0]
+	if entry.Name != "test.db" || entry.Caller == nil || !strings.HasSuffix(entry.Caller.File, "logxtest_test.go") {
+		t.Errorf("expected name and caller recorded, actual %s %v", entry.Name, entry.Caller)
+	}
+
+	fake := &fakeT{}
+	logs.AssertLogged(fake, logx.InfoLevel, "login", logx.Fields{"user": "jerry"})
+	logs.AssertNotLogged(fake, logx.InfoLevel, "login")
+	if len(fake.errors) != 2 {
+		t.Errorf("expected assertions failed, actual %v", fake.errors)
+	}
+
+	if taken := logs.TakeAll(); len(taken) != 2 || logs.Len() != 0 {
+		t.Errorf("expected entries taken, actual %d left", logs.Len())
+	}
+}
+
+func TestObserveStd(t *testing.T) {
+	logs := ObserveStd(t)
+	logx.Warnw("disk almost full", logx.Float64("used", 0.95))
+	logs.AssertLogged(t, logx.WarnLevel, "disk", logx.Fields{"used": 0.95})
+}
+
+func TestStubExit(t *testing.T) {
+	logger, logs := NewObserved()
+	exit := StubExit(t, logger)
+	if exit.Exited() {
+		t.Errorf("expected no exit")
+	}
+
+	logger.WithField("k", "v").Fatal("entry")
+	if exit.Exited() {
+		t.Errorf("expected no exit from Entry.Fatal")
+	}
+	logger.Named("child").Fatal("bye")
+	if !exit.Exited() || exit.Code() != 1 {
+		t.Errorf("expected exit code 1, actual %d", exit.Code())
+	}
+	logs.AssertLogged(t, logx.FatalLevel, "bye", nil)
+}
+
+func TestNewLogger(t *testing.T) {
+	fake := &recordT{TB: t}
+	logger := NewLogger(fake)
+	logger.Infow("hello", logx.String("k", "v"))
+	if len(fake.logs) != 1 || !strings.HasSuffix(fake.logs[0], " INFO - hello k=v") {
+		t.Errorf("expected the entry written to t.Log, actual %q", fake.logs)
+	}
+}
+
+type recordT struct {
+	testing.TB
+	logs []string
+}
+
+func (t *recordT) Log(args ...interface{}) {
+	t.logs = append(t.logs, args[0].(string))
+}
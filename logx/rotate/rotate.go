@@ -0,0 +1,391 @@
+// Package rotate provide a rotating file writer which can be used as the output of logx.
+//
+//	w, err := rotate.New("/var/log/app/app.log", rotate.WithMaxSize(100<<20),
+//		rotate.WithSchedule(rotate.Daily), rotate.WithCompress(true), rotate.WithMaxBackups(7))
+//	logx.Init("app", logx.WithOutput(w))
+//
+// Rotate can also be triggered manually, e.g. on SIGHUP.
+package rotate
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+	compressSuffix   = ".gz"
+
+	// retryDelay is the time writes append to the current file after a failed rotation
+	retryDelay = 10 * time.Second
+)
+
+// now and rename are replaced in tests.
+var (
+	now    = time.Now
+	rename = os.Rename
+)
+
+// Schedule is the time based rotation period.
+type Schedule int
+
+const (
+	None Schedule = iota
+	Hourly
+	Daily
+)
+
+// Writer is an io.WriteCloser which writes to the file and rotates it by size and/or time.
+// Backups are named as name-2006-01-02T15-04-05.000.ext in the same directory,
+// the time is moved forward by a millisecond when the name is taken by an earlier backup.
+type Writer struct {
+	filename   string
+	maxSize    int64
+	schedule   Schedule
+	maxAge     time.Duration
+	maxBackups int
+	compress   bool
+
+	mu         sync.Mutex
+	file       *os.File
+	size       int64
+	nextRotate time.Time
+	retryAt    time.Time
+	closed     bool
+
+	millCh chan struct{}
+	millWg sync.WaitGroup
+}
+
+type Option func(w *Writer)
+
+// WithMaxSize rotates the file before it grows beyond maxSize bytes, 0 means no limit.
+func WithMaxSize(maxSize int64) Option {
+	return func(w *Writer) {
+		w.maxSize = maxSize
+	}
+}
+
+// WithSchedule rotates the file at the beginning of every hour or day.
+func WithSchedule(schedule Schedule) Option {
+	return func(w *Writer) {
+		w.schedule = schedule
+	}
+}
+
+// WithMaxAge removes backups older than maxAge, 0 means never.
+func WithMaxAge(maxAge time.Duration) Option {
+	return func(w *Writer) {
+		w.maxAge = maxAge
+	}
+}
+
+// WithMaxBackups keeps at most maxBackups backups, 0 means all.
+func WithMaxBackups(maxBackups int) Option {
+	return func(w *Writer) {
+		w.maxBackups = maxBackups
+	}
+}
+
+// WithCompress gzip the backups in the background.
+func WithCompress(compress bool) Option {
+	return func(w *Writer) {
+		w.compress = compress
+	}
+}
+
+func New(filename string, opts ...Option) (*Writer, error) {
+	w := &Writer{
+		filename: filename,
+		millCh:   make(chan struct{}, 1),
+	}
+	for _, o := range opts {
+		o(w)
+	}
+
+	if err := w.openExisting(); err != nil {
+		return nil, err
+	}
+	w.nextRotate = w.nextRotateTime(now())
+
+	w.millWg.Add(1)
+	go w.runMill()
+	w.mill()
+	return w, nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.file == nil {
+		if err := w.openExisting(); err != nil {
+			return 0, err
+		}
+	}
+
+	t := now()
+	if !t.Before(w.retryAt) && (w.schedule != None && !t.Before(w.nextRotate) ||
+		w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize) {
+		if err := w.rotate(t); err != nil {
+			if w.file == nil {
+				return 0, err
+			}
+			// keep writing to the current file, the rotation is tried again after retryDelay
+			w.retryAt = t.Add(retryDelay)
+			_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, moves it aside as a backup and opens a new file.
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate(now())
+}
+
+// Close closes the file and waits for the background compression and cleanup.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+	}
+	close(w.millCh)
+	w.mu.Unlock()
+
+	w.millWg.Wait()
+	return err
+}
+
+func (w *Writer) openExisting() error {
+	if err := os.MkdirAll(filepath.Dir(w.filename), 0755); err != nil {
+		return fmt.Errorf("rotate: can't make directories for %s: %v", w.filename, err)
+	}
+	file, err := os.OpenFile(w.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("rotate: can't open %s: %v", w.filename, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("rotate: can't stat %s: %v", w.filename, err)
+	}
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+// rotate moves the file aside and opens a new one. On failure the original file is reopened to append to,
+// w.file is nil only if it can't be opened.
+func (w *Writer) rotate(t time.Time) error {
+	var err error
+	if w.file != nil {
+		if closeErr := w.file.Close(); closeErr != nil {
+			err = fmt.Errorf("rotate: can't close %s: %v", w.filename, closeErr)
+		}
+		w.file = nil
+	}
+	if err == nil && w.size > 0 {
+		if renameErr := rename(w.filename, w.backupName(t)); renameErr != nil {
+			err = fmt.Errorf("rotate: can't rename %s: %v", w.filename, renameErr)
+		}
+	}
+	if openErr := w.openExisting(); openErr != nil && err == nil {
+		err = openErr
+	}
+	if err != nil {
+		return err
+	}
+	w.nextRotate = w.nextRotateTime(t)
+	w.retryAt = time.Time{}
+	w.mill()
+	return nil
+}
+
+func (w *Writer) nextRotateTime(t time.Time) time.Time {
+	switch w.schedule {
+	case Hourly:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+	case Daily:
+		return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Time{}
+}
+
+func (w *Writer) prefixAndExt() (string, string) {
+	base := filepath.Base(w.filename)
+	ext := filepath.Ext(base)
+	return base[:len(base)-len(ext)] + "-", ext
+}
+
+// backupName returns an unused name for the backup rotated at t,
+// so a second rotation in the same millisecond doesn't overwrite the first backup.
+func (w *Writer) backupName(t time.Time) string {
+	prefix, ext := w.prefixAndExt()
+	for {
+		name := filepath.Join(filepath.Dir(w.filename), prefix+t.Format(backupTimeFormat)+ext)
+		if !exists(name) && !exists(name+compressSuffix) {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func exists(name string) bool {
+	_, err := os.Lstat(name)
+	return !os.IsNotExist(err)
+}
+
+// mill notifies the background goroutine without blocking, requests are merged.
+func (w *Writer) mill() {
+	if w.compress || w.maxAge > 0 || w.maxBackups > 0 {
+		select {
+		case w.millCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (w *Writer) runMill() {
+	defer w.millWg.Done()
+	for range w.millCh {
+		if err := w.millOnce(); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "rotate: %v\n", err)
+		}
+	}
+}
+
+type backup struct {
+	path      string
+	timestamp time.Time
+}
+
+// millOnce compresses the backups and removes the expired ones.
+func (w *Writer) millOnce() error {
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+
+	var remove []backup
+	if w.maxBackups > 0 && len(backups) > w.maxBackups {
+		remove = append(remove, backups[w.maxBackups:]...)
+		backups = backups[:w.maxBackups]
+	}
+	if w.maxAge > 0 {
+		cutoff := now().Add(-w.maxAge)
+		kept := backups[:0]
+		for _, b := range backups {
+			if b.timestamp.Before(cutoff) {
+				remove = append(remove, b)
+			} else {
+				kept = append(kept, b)
+			}
+		}
+		backups = kept
+	}
+
+	for _, b := range remove {
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("can't remove backup %s: %v", b.path, err)
+		}
+	}
+
+	if w.compress {
+		for _, b := range backups {
+			if strings.HasSuffix(b.path, compressSuffix) {
+				continue
+			}
+			if err := compressFile(b.path, b.path+compressSuffix); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// backups returns the backups sorted by time, newest first.
+func (w *Writer) backups() ([]backup, error) {
+	dir := filepath.Dir(w.filename)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("can't read log directory %s: %v", dir, err)
+	}
+
+	prefix, ext := w.prefixAndExt()
+	var backups []backup
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), compressSuffix)
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		ts := name[len(prefix) : len(name)-len(ext)]
+		t, err := time.ParseInLocation(backupTimeFormat, ts, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, e.Name()), timestamp: t})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].timestamp.After(backups[j].timestamp)
+	})
+	return backups, nil
+}
+
+func compressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("can't open backup %s: %v", src, err)
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("can't create %s: %v", tmp, err)
+	}
+	gz := gzip.NewWriter(out)
+	if _, err = io.Copy(gz, in); err == nil {
+		err = gz.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("can't compress %s: %v", src, err)
+	}
+
+	if err := os.Rename(tmp, dst); err != nil {
+		return fmt.Errorf("can't rename %s: %v", tmp, err)
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,177 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setNow fixes the clock of the writer, returns a function to move it forward.
+func setNow(t *testing.T, start time.Time) func(d time.Duration) {
+	current := start
+	now = func() time.Time { return current }
+	t.Cleanup(func() { now = time.Now })
+	return func(d time.Duration) { current = current.Add(d) }
+}
+
+func listDir(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestRotateBySize(t *testing.T) {
+	dir := t.TempDir()
+	advance := setNow(t, time.Date(2022, time.April, 8, 13, 11, 13, 0, time.Local))
+
+	w, err := New(filepath.Join(dir, "app.log"), WithMaxSize(10))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	_, _ = w.Write([]byte("12345678\n"))
+	advance(time.Second)
+	_, _ = w.Write([]byte("abcdefgh\n"))
+	_ = w.Close()
+
+	names := listDir(t, dir)
+	expected := []string{"app-2022-04-08T13-11-14.000.log", "app.log"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected files %v, actual %v", expected, names)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+	if string(data) != "abcdefgh\n" {
+		t.Errorf("expected current file to hold the last write, actual %q", data)
+	}
+}
+
+func TestRotateBySchedule(t *testing.T) {
+	dir := t.TempDir()
+	advance := setNow(t, time.Date(2022, time.April, 8, 23, 59, 0, 0, time.Local))
+
+	w, err := New(filepath.Join(dir, "app.log"), WithSchedule(Daily))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	_, _ = w.Write([]byte("day 1\n"))
+	advance(time.Minute)
+	_, _ = w.Write([]byte("day 2\n"))
+	_ = w.Close()
+
+	if names := listDir(t, dir); len(names) != 2 || names[0] != "app-2022-04-09T00-00-00.000.log" {
+		t.Errorf("expected a backup at midnight, actual %v", names)
+	}
+}
+
+func TestCompressAndPrune(t *testing.T) {
+	dir := t.TempDir()
+	advance := setNow(t, time.Date(2022, time.April, 8, 13, 11, 13, 0, time.Local))
+
+	w, err := New(filepath.Join(dir, "app.log"), WithCompress(true), WithMaxBackups(2))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		_, _ = w.Write([]byte("line\n"))
+		advance(time.Minute)
+		if err := w.Rotate(); err != nil {
+			t.Fatalf("Rotate() error: %v", err)
+		}
+	}
+	_ = w.Close()
+
+	names := listDir(t, dir)
+	expected := []string{"app-2022-04-08T13-14-13.000.log.gz", "app-2022-04-08T13-15-13.000.log.gz", "app.log"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected files %v, actual %v", expected, names)
+	}
+
+	f, _ := os.Open(filepath.Join(dir, names[0]))
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("invalid gzip file: %v", err)
+	}
+	if data, _ := io.ReadAll(gz); string(data) != "line\n" {
+		t.Errorf("expected compressed content %q, actual %q", "line\n", data)
+	}
+}
+
+func TestRotateSameMillisecond(t *testing.T) {
+	dir := t.TempDir()
+	setNow(t, time.Date(2022, time.April, 8, 13, 11, 13, 0, time.Local))
+
+	w, err := New(filepath.Join(dir, "app.log"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	for _, line := range []string{"first\n", "second\n"} {
+		_, _ = w.Write([]byte(line))
+		if err := w.Rotate(); err != nil {
+			t.Fatalf("Rotate() error: %v", err)
+		}
+	}
+	_ = w.Close()
+
+	names := listDir(t, dir)
+	expected := []string{"app-2022-04-08T13-11-13.000.log", "app-2022-04-08T13-11-13.001.log", "app.log"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected files %v, actual %v", expected, names)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, names[0])); string(data) != "first\n" {
+		t.Errorf("expected first backup kept, actual %q", data)
+	}
+}
+
+func TestRotateRenameFailure(t *testing.T) {
+	dir := t.TempDir()
+	advance := setNow(t, time.Date(2022, time.April, 8, 13, 11, 13, 0, time.Local))
+	renames, fail := 0, true
+	rename = func(from, to string) error {
+		renames++
+		if fail {
+			return os.ErrPermission
+		}
+		return os.Rename(from, to)
+	}
+	t.Cleanup(func() { rename = os.Rename })
+
+	w, err := New(filepath.Join(dir, "app.log"), WithMaxSize(10))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	_, _ = w.Write([]byte("12345678\n"))
+	if err := w.Rotate(); err == nil {
+		t.Errorf("expected Rotate() error")
+	}
+	// appended to the original file, the rotation is not retried until retryDelay passed
+	for _, line := range []string{"abcdefgh\n", "x\n", "y\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Errorf("Write() error: %v", err)
+		}
+	}
+	if renames != 2 {
+		t.Errorf("expected 2 renames before retryDelay, actual %d", renames)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+	if string(data) != "12345678\nabcdefgh\nx\ny\n" {
+		t.Errorf("expected writes kept in the original file, actual %q", data)
+	}
+
+	fail = false
+	advance(retryDelay)
+	_, _ = w.Write([]byte("rotated\n"))
+	_ = w.Close()
+	if data, _ := os.ReadFile(filepath.Join(dir, "app.log")); string(data) != "rotated\n" {
+		t.Errorf("expected rotated after retryDelay, actual %q", data)
+	}
+}
@@ -0,0 +1,162 @@
+package logx
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const samplingBuckets = 4096
+
+// Sampling limits the entries written during a flood, e.g. an Errorf in a loop.
+type Sampling struct {
+	// First entries of the same level and message are logged per Interval, then every Thereafter-th.
+	// Thereafter <= 0 drops all the others.
+	First      int
+	Thereafter int
+	// Interval defaults to one second.
+	Interval time.Duration
+
+	// PerSecond caps the total entries per second, 0 means no cap.
+	PerSecond int
+
+	// ReportInterval is the period of the summaries of the suppressed entries, defaults to 10 seconds.
+	ReportInterval time.Duration
+}
+
+// WithSampling enables sampling. A background goroutine writes a summary entry of the suppressed entries
+// at WarnLevel with a "suppressed" field every ReportInterval, unless warn is disabled for the logger.
+// Close the logger to write the last summary and stop the goroutine.
+func WithSampling(sampling Sampling) Option {
+	return func(l *LogX) {
+		if l.sampler != nil {
+			l.sampler.stop()
+		}
+		l.sampler = newSampler(sampling)
+		l.sampler.owner = l
+		go l.sampler.run(l)
+	}
+}
+
+type sampler struct {
+	Sampling
+	counters [samplingBuckets]counter
+
+	second      int64
+	secondCount int64
+
+	suppressed uint64
+
+	// owner is the logger which started the goroutine, children share the sampler
+	owner    *LogX
+	stopOnce sync.Once
+	stopping chan struct{}
+	done     chan struct{}
+}
+
+func newSampler(sampling Sampling) *sampler {
+	if sampling.Interval <= 0 {
+		sampling.Interval = time.Second
+	}
+	if sampling.ReportInterval <= 0 {
+		sampling.ReportInterval = 10 * time.Second
+	}
+	return &sampler{Sampling: sampling, stopping: make(chan struct{}), done: make(chan struct{})}
+}
+
+// run writes the summary every ReportInterval until stop, and once more before it returns.
+func (s *sampler) run(l *LogX) {
+	defer close(s.done)
+	ticker := time.NewTicker(s.ReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.report(l, now)
+		case <-s.stopping:
+			s.report(l, time.Now())
+			return
+		}
+	}
+}
+
+// stop writes the last summary and waits for the goroutine.
+func (s *sampler) stop() {
+	s.stopOnce.Do(func() { close(s.stopping) })
+	<-s.done
+}
+
+// allow reports whether the entry should be logged, it is counted as suppressed otherwise.
+func (s *sampler) allow(e *Entry) bool {
+	t := e.Time.UnixNano()
+	if s.First > 0 || s.Thereafter > 0 {
+		n := s.counters[samplingKey(e.Level, e.Message)].inc(t, s.Interval)
+		if n > uint64(s.First) && (s.Thereafter <= 0 || (n-uint64(s.First))%uint64(s.Thereafter) != 0) {
+			atomic.AddUint64(&s.suppressed, 1)
+			return false
+		}
+	}
+
+	if s.PerSecond > 0 {
+		sec := t / int64(time.Second)
+		if old := atomic.LoadInt64(&s.second); old != sec && atomic.CompareAndSwapInt64(&s.second, old, sec) {
+			atomic.StoreInt64(&s.secondCount, 0)
+		}
+		if atomic.AddInt64(&s.secondCount, 1) > int64(s.PerSecond) {
+			atomic.AddUint64(&s.suppressed, 1)
+			return false
+		}
+	}
+	return true
+}
+
+// report writes the summary of the entries suppressed since the last one. The count is kept
+// while warn is disabled, so it's reported once the level allows it.
+func (s *sampler) report(l *LogX, now time.Time) {
+	if atomic.LoadUint64(&s.suppressed) == 0 || !l.IsLevelEnabled(WarnLevel) {
+		return
+	}
+
+	n := atomic.SwapUint64(&s.suppressed, 0)
+	entry := l.WithField("suppressed", n)
+	entry.Time = now
+	entry.Level = WarnLevel
+	entry.Message = fmt.Sprintf("logx: suppressed %d entries by sampling", n)
+	entry.fire()
+}
+
+// samplingKey hashes level and message by FNV-1a without allocation.
+func samplingKey(level Level, msg string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	h := uint32(offset32)
+	h ^= uint32(level)
+	h *= prime32
+	for i := 0; i < len(msg); i++ {
+		h ^= uint32(msg[i])
+		h *= prime32
+	}
+	return h % samplingBuckets
+}
+
+type counter struct {
+	resetAt int64
+	count   uint64
+}
+
+func (c *counter) inc(t int64, interval time.Duration) uint64 {
+	resetAt := atomic.LoadInt64(&c.resetAt)
+	if resetAt > t {
+		return atomic.AddUint64(&c.count, 1)
+	}
+
+	atomic.StoreUint64(&c.count, 1)
+	if !atomic.CompareAndSwapInt64(&c.resetAt, resetAt, t+int64(interval)) {
+		// reset by another goroutine
+		return atomic.AddUint64(&c.count, 1)
+	}
+	return 1
+}
@@ -0,0 +1,110 @@
+package logx
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// captureHook records the fired entries.
+type captureHook struct {
+	mu      sync.Mutex
+	entries []*Entry
+}
+
+func (h *captureHook) SetLogger(*LogX) {}
+
+func (h *captureHook) Levels() []Level {
+	return AllLevels()
+}
+
+func (h *captureHook) Fire(entry *Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = append(h.entries, &Entry{Level: entry.Level, Message: entry.Message, Fields: entry.AllFields(),
+		Stack: entry.Stack, Err: entry.Err})
+	return nil
+}
+
+func (h *captureHook) messages() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var messages []string
+	for _, e := range h.entries {
+		messages = append(messages, e.Message)
+	}
+	return messages
+}
+
+func TestSampling(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := NewLogx("test", WithOutput(&buffer), WithSampling(Sampling{First: 2, Thereafter: 3, Interval: time.Hour}))
+	for i := 1; i <= 10; i++ {
+		logger.Errorf("boom")
+	}
+	// 1, 2, 5, 8
+	if lines := strings.Count(buffer.String(), "boom"); lines != 4 {
+		t.Errorf("expected 4 sampled entries, actual %d", lines)
+	}
+}
+
+func TestSamplingPerSecond(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := NewLogx("test", WithOutput(&buffer), WithSampling(Sampling{PerSecond: 3}))
+	for i := 0; i < 10; i++ {
+		logger.Infof("message %d", i)
+	}
+	if lines := strings.Count(buffer.String(), "message"); lines > 3 {
+		t.Errorf("expected at most 3 entries per second, actual %d", lines)
+	}
+}
+
+func TestSamplingReport(t *testing.T) {
+	hook := &captureHook{}
+	logger := NewLogx("test", WithOutput(nil), WithHook(hook),
+		WithSampling(Sampling{First: 1, Interval: time.Hour, ReportInterval: 10 * time.Millisecond}))
+	defer logger.Close()
+	for i := 0; i < 3; i++ {
+		logger.Error("boom")
+	}
+
+	// the summary is written after the flood stopped
+	expected := []string{"boom", "logx: suppressed 2 entries by sampling"}
+	deadline := time.Now().Add(3 * time.Second)
+	for len(hook.messages()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if actual := hook.messages(); strings.Join(actual, "|") != strings.Join(expected, "|") {
+		t.Fatalf("expected %v, actual %v", expected, actual)
+	}
+	if n := hook.entries[1].Fields["suppressed"]; n != uint64(2) {
+		t.Errorf("expected suppressed field 2, actual %v", n)
+	}
+}
+
+func TestSamplingReportOnClose(t *testing.T) {
+	hook := &captureHook{}
+	logger := NewLogx("test", WithOutput(nil), WithHook(hook),
+		WithSampling(Sampling{First: 1, Interval: time.Hour, ReportInterval: time.Hour}))
+	for i := 0; i < 3; i++ {
+		logger.Error("boom")
+	}
+	_ = logger.Close()
+	expected := []string{"boom", "logx: suppressed 2 entries by sampling"}
+	if actual := hook.messages(); strings.Join(actual, "|") != strings.Join(expected, "|") {
+		t.Fatalf("expected %v, actual %v", expected, actual)
+	}
+
+	// the summary respects the level of the logger
+	hook.entries = nil
+	silent := NewLogx("test", WithOutput(nil), WithHook(hook), WithLevel(ErrorLevel),
+		WithSampling(Sampling{First: 1, Interval: time.Hour, ReportInterval: time.Hour}))
+	silent.Error("boom")
+	silent.Error("boom")
+	_ = silent.Close()
+	if actual := hook.messages(); len(actual) != 1 {
+		t.Errorf("expected no summary with warn disabled, actual %v", actual)
+	}
+}
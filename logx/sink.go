@@ -0,0 +1,150 @@
+package logx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+)
+
+// Sink is a destination with its own formatter and level range, e.g. errors to stderr as colored text
+// and everything to a file as json. Once sinks are added they replace the output of the logger.
+type Sink struct {
+	Out       io.Writer
+	Formatter Formatter
+
+	// MinLevel is the least severe level written, e.g. InfoLevel writes info and above.
+	MinLevel Level
+	// MaxLevel is the most severe level written, the zero value PanicLevel means no upper bound.
+	MaxLevel Level
+}
+
+func NewSink(out io.Writer, formatter Formatter, minLevel Level) *Sink {
+	return &Sink{Out: out, Formatter: formatter, MinLevel: minLevel, MaxLevel: PanicLevel}
+}
+
+// Enabled reports whether the sink writes entries of the level.
+func (s *Sink) Enabled(level Level) bool {
+	return level <= s.MinLevel && level >= s.MaxLevel
+}
+
+func WithSink(sink *Sink) Option {
+	return func(l *LogX) {
+		l.AddSink(sink)
+	}
+}
+
+// AddSink is safe to call while logging.
+func (l *LogX) AddSink(sink *Sink) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sinks := l.loadSinks()
+	l.sinks.Store(append(sinks[:len(sinks):len(sinks)], sink))
+}
+
+// SetSinks replaces all the sinks, no sink means writing to the output of the logger.
+func (l *LogX) SetSinks(sinks ...*Sink) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sinks.Store(append([]*Sink(nil), sinks...))
+}
+
+// loadSinks returns the sinks of the logger, the slice is never modified in place.
+func (l *LogX) loadSinks() []*Sink {
+	sinks, _ := l.sinks.Load().([]*Sink)
+	return sinks
+}
+
+// getSinks returns the sinks of the logger or the nearest ancestor which has some.
+func (l *LogX) getSinks() []*Sink {
+	for logger := l; logger != nil; logger = logger.parent {
+		if sinks := logger.loadSinks(); len(sinks) > 0 {
+			return sinks
+		}
+	}
+	return nil
+}
+
+// writers returns the output and the writers of the sinks.
+func (l *LogX) writers() []io.Writer {
+	var writers []io.Writer
+	if out := l.output(); out != nil {
+		writers = append(writers, out)
+	}
+	for _, s := range l.getSinks() {
+		if s.Out != nil {
+			writers = append(writers, s.Out)
+		}
+	}
+	return writers
+}
+
+// sameFormatter compares pointers only, comparing other values may panic, e.g. a struct holding a map.
+func sameFormatter(a, b Formatter) bool {
+	t := reflect.TypeOf(a)
+	return t != nil && t.Kind() == reflect.Ptr && t == reflect.TypeOf(b) && a == b
+}
+
+type formatted struct {
+	formatter Formatter
+	buffer    *bytes.Buffer
+	data      []byte
+	err       error
+}
+
+// writeSinks formats the entry once per distinct formatter and writes it to the enabled sinks.
+func (e *Entry) writeSinks(sinks []*Sink) {
+	var results []formatted
+	defer func() {
+		for _, r := range results {
+			r.buffer.Reset()
+			bufferPool.Put(r.buffer)
+		}
+		e.Buffer = nil
+	}()
+
+	format := func(formatter Formatter) formatted {
+		for _, r := range results {
+			if sameFormatter(r.formatter, formatter) {
+				return r
+			}
+		}
+		buffer := bufferPool.Get().(*bytes.Buffer)
+		buffer.Reset()
+		e.Buffer = buffer
+		data, err := formatter.Format(e)
+		r := formatted{formatter: formatter, buffer: buffer, data: data, err: err}
+		results = append(results, r)
+		return r
+	}
+
+	type pending struct {
+		out  io.Writer
+		data []byte
+	}
+	var writes []pending
+	for _, s := range sinks {
+		if s.Out == nil || !s.Enabled(e.Level) {
+			continue
+		}
+		formatter := s.Formatter
+		if formatter == nil {
+			formatter = e.loadSettings().formatter
+		}
+		r := format(formatter)
+		if r.err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Failed to format logger, %v\n", r.err)
+			continue
+		}
+		writes = append(writes, pending{out: s.Out, data: r.data})
+	}
+
+	e.Logger.mu.Lock()
+	defer e.Logger.mu.Unlock()
+	for _, w := range writes {
+		if _, err := w.out.Write(w.data); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Failed to write to Output, %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package logx
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// countFormatter counts the calls of Format.
+type countFormatter struct {
+	Formatter
+	count int
+}
+
+func (f *countFormatter) Format(entry *Entry) ([]byte, error) {
+	f.count++
+	return f.Formatter.Format(entry)
+}
+
+func TestSinks(t *testing.T) {
+	var stderr, errorLog, appLog, stdout bytes.Buffer
+	text := &countFormatter{Formatter: NewTextFormatter(nil, false)}
+	json := &countFormatter{Formatter: NewJSONFormatter()}
+
+	logger := NewLogx("app", WithLevel(DebugLevel),
+		WithSink(NewSink(&stderr, text, ErrorLevel)),
+		WithSink(NewSink(&errorLog, json, ErrorLevel)),
+		WithSink(NewSink(&appLog, json, DebugLevel)),
+		WithSink(&Sink{Out: &stdout, Formatter: text, MinLevel: InfoLevel, MaxLevel: WarnLevel}))
+
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Error("error")
+
+	tests := []struct {
+		name     string
+		buffer   *bytes.Buffer
+		expected []string
+	}{
+		{"stderr", &stderr, []string{"error"}},
+		{"error.log", &errorLog, []string{"error"}},
+		{"app.log", &appLog, []string{"debug", "info", "error"}},
+		{"stdout", &stdout, []string{"info"}},
+	}
+	for _, test := range tests {
+		lines := strings.Split(strings.TrimSpace(test.buffer.String()), "\n")
+		if len(lines) != len(test.expected) {
+			t.Errorf("Test %s: expected %d lines, actual %q", test.name, len(test.expected), test.buffer.String())
+			continue
+		}
+		for i, msg := range test.expected {
+			if !strings.Contains(lines[i], msg) {
+				t.Errorf("Test %s: expected %q in line %q", test.name, msg, lines[i])
+			}
+		}
+	}
+
+	// formatted once per distinct formatter: text for info and error, json for all
+	if text.count != 2 || json.count != 3 {
+		t.Errorf("expected 2 text and 3 json formats, actual %d and %d", text.count, json.count)
+	}
+	if !strings.HasPrefix(errorLog.String(), "{") {
+		t.Errorf("expected json in error.log, actual %q", errorLog.String())
+	}
+}
+
+// mapFormatter is a formatter value which can't be compared.
+type mapFormatter struct {
+	prefix map[Level]string
+}
+
+func (f mapFormatter) Format(entry *Entry) ([]byte, error) {
+	return []byte(f.prefix[entry.Level] + entry.Message + "\n"), nil
+}
+
+func TestSinksValueFormatter(t *testing.T) {
+	var first, second bytes.Buffer
+	formatter := mapFormatter{prefix: map[Level]string{InfoLevel: "I "}}
+	logger := NewLogx("app", WithoutEnv(),
+		WithSink(NewSink(&first, formatter, InfoLevel)), WithSink(NewSink(&second, formatter, InfoLevel)))
+
+	logger.Info("hello")
+	if first.String() != "I hello\n" || second.String() != "I hello\n" {
+		t.Errorf("expected both sinks written, actual %q and %q", first.String(), second.String())
+	}
+}
+
+func TestAddSinkWhileLogging(t *testing.T) {
+	logger := NewLogx("app", WithoutEnv(), WithOutput(nil))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			logger.Info("racing")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		logger.AddSink(NewSink(io.Discard, nil, InfoLevel))
+		if i%10 == 0 {
+			logger.SetSinks()
+		}
+	}
+	<-done
+}
@@ -0,0 +1,155 @@
+package logx
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// LevelToSlog maps the level to slog, the built-in levels are mapped as
+// trace -8, debug -4, info 0, warn 4, error 8, fatal 12 and panic 16, custom levels in between.
+func LevelToSlog(level Level) slog.Level {
+	return slog.Level((InfoLevel - level) * 4 / 10)
+}
+
+// LevelFromSlog maps the slog level to the nearest registered level, e.g. slog.LevelWarn to WarnLevel.
+func LevelFromSlog(level slog.Level) Level {
+	exact := InfoLevel - Level(level)*10/4
+	nearest, distance := exact, math.MaxInt
+	for _, l := range AllLevels() {
+		d := int(l - exact)
+		if d < 0 {
+			d = -d
+		}
+		// ties go to the more severe level, AllLevels is sorted from the most severe
+		if d < distance {
+			nearest, distance = l, d
+		}
+	}
+	return nearest
+}
+
+// SlogHandler is a slog.Handler backed by a LogX, so slog.New(logx.NewSlogHandler(logger)) uses the
+// formatters and hooks of the logger. Attrs are added as Fields, the keys in a group are prefixed with
+// the group name, e.g. "req.method".
+type SlogHandler struct {
+	logger *LogX
+	fields Fields
+	prefix string
+}
+
+func NewSlogHandler(logger *LogX) *SlogHandler {
+	return &SlogHandler{logger: logger}
+}
+
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.IsLevelEnabled(LevelFromSlog(level))
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make(Fields, len(h.fields)+r.NumAttrs())
+	for k, v := range h.fields {
+		fields[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addSlogAttr(fields, h.prefix, a)
+		return true
+	})
+
+	entry := h.logger.WithFields(fields)
+	entry.Time = r.Time
+	entry.Context = ctx
+	entry.pc = r.PC
+	entry.Log(1, LevelFromSlog(r.Level), r.Message)
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	c := *h
+	c.fields = make(Fields, len(h.fields)+len(attrs))
+	for k, v := range h.fields {
+		c.fields[k] = v
+	}
+	for _, a := range attrs {
+		addSlogAttr(c.fields, h.prefix, a)
+	}
+	return &c
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.prefix = h.prefix + name + "."
+	return &c
+}
+
+// addSlogAttr adds the attr by the rules of slog: empty attrs are ignored
+// and the attrs of a group without key are inlined.
+func addSlogAttr(fields Fields, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			addSlogAttr(fields, prefix, ga)
+		}
+		return
+	}
+	fields[prefix+a.Key] = a.Value.Any()
+}
+
+// SlogFormatter forwards the entries to a slog.Handler instead of formatting them, it returns no bytes.
+// Fields are passed as attrs, Err as "error" and the caller as the PC of the record.
+type SlogFormatter struct {
+	Handler slog.Handler
+}
+
+// NewSlogSink returns a sink which forwards the entries at or above minLevel to the handler.
+func NewSlogSink(handler slog.Handler, minLevel Level) *Sink {
+	return NewSink(io.Discard, &SlogFormatter{Handler: handler}, minLevel)
+}
+
+func (f *SlogFormatter) Format(entry *Entry) ([]byte, error) {
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	level := LevelToSlog(entry.Level)
+	if !f.Handler.Enabled(ctx, level) {
+		return nil, nil
+	}
+
+	var pc uintptr
+	if entry.Caller != nil {
+		pc = entry.Caller.PC
+	}
+	t := entry.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	r := slog.NewRecord(t, level, entry.Message, pc)
+	for _, k := range sortedKeys(entry.Fields) {
+		r.AddAttrs(slog.Any(k, entry.Fields[k]))
+	}
+	for _, field := range entry.TypedFields {
+		r.AddAttrs(slog.Any(field.Key, field.Value()))
+	}
+	if entry.Err != nil {
+		r.AddAttrs(slog.Any("error", entry.Err))
+	}
+	if len(entry.Stack) > 0 {
+		r.AddAttrs(slog.Any("stacktrace", stackStrings(entry.Stack)))
+	}
+	return nil, f.Handler.Handle(ctx, r)
+}
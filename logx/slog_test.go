@@ -0,0 +1,104 @@
+package logx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogLevels(t *testing.T) {
+	for level, expected := range map[Level]slog.Level{
+		TraceLevel: -8,
+		DebugLevel: slog.LevelDebug,
+		InfoLevel:  slog.LevelInfo,
+		WarnLevel:  slog.LevelWarn,
+		ErrorLevel: slog.LevelError,
+		FatalLevel: 12,
+		PanicLevel: 16,
+	} {
+		if actual := LevelToSlog(level); actual != expected {
+			t.Errorf("%s: expected slog level %v, actual %v", level, expected, actual)
+		}
+		if actual := LevelFromSlog(expected); actual != level {
+			t.Errorf("%v: expected level %s, actual %s", expected, level, actual)
+		}
+	}
+	if actual := LevelFromSlog(slog.LevelInfo + 1); actual != InfoLevel {
+		t.Errorf("expected INFO+1 mapped to info, actual %s", actual)
+	}
+	if actual := LevelFromSlog(100); actual != PanicLevel {
+		t.Errorf("expected a severe level mapped to panic, actual %s", actual)
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := NewLogx("test", WithOutput(&buffer), WithFormatter(NewJSONFormatter()), WithReportCaller(true),
+		WithLevel(InfoLevel))
+	sl := slog.New(NewSlogHandler(logger.With(Fields{"app": "demo"})))
+
+	sl.Debug("dropped")
+	sl.With("user", "tom").WithGroup("req").With("method", "GET").
+		Warn("slow request", "cost", 3, slog.Group("client", "ip", "10.0.0.1"), slog.Group("", "inline", true))
+
+	var actual map[string]interface{}
+	if err := json.Unmarshal(buffer.Bytes(), &actual); err != nil {
+		t.Fatalf("expected one json entry, actual %q: %v", buffer.String(), err)
+	}
+	expected := map[string]interface{}{
+		"level":         "warn",
+		"msg":           "slow request",
+		"app":           "demo",
+		"user":          "tom",
+		"req.method":    "GET",
+		"req.cost":      float64(3),
+		"req.client.ip": "10.0.0.1",
+		"req.inline":    true,
+		"instance":      "test-0",
+		"name":          "test",
+	}
+	for k, v := range expected {
+		if actual[k] != v {
+			t.Errorf("key %s: expected %v, actual %v", k, v, actual[k])
+		}
+	}
+	// the caller is the PC of the record rather than a frame of slog
+	if caller, _ := actual["caller"].(string); !strings.HasPrefix(caller, "slog_test.go:") {
+		t.Errorf("expected the caller of slog, actual %v", actual["caller"])
+	}
+}
+
+func TestSlogSink(t *testing.T) {
+	var buffer bytes.Buffer
+	handler := slog.NewJSONHandler(&buffer, &slog.HandlerOptions{AddSource: true, Level: slog.LevelInfo})
+	logger := NewLogx("test", WithSink(NewSlogSink(handler, TraceLevel)), WithReportCaller(true))
+
+	logger.Debug("dropped by the handler")
+	logger.WithField("user", "tom").WithError(errors.New("boom")).Errorw("failed", Int("retry", 2))
+
+	var actual struct {
+		Level  string
+		Msg    string
+		User   string
+		Retry  int
+		Error  string
+		Source struct{ Line int }
+	}
+	if err := json.Unmarshal(buffer.Bytes(), &actual); err != nil {
+		t.Fatalf("expected one json record, actual %q: %v", buffer.String(), err)
+	}
+	if actual.Level != "ERROR" || actual.Msg != "failed" || actual.User != "tom" || actual.Retry != 2 ||
+		actual.Error != "boom" || actual.Source.Line == 0 {
+		t.Errorf("unexpected record %s", buffer.String())
+	}
+
+	buffer.Reset()
+	logger.WithContext(context.Background()).Info("with context")
+	if !strings.Contains(buffer.String(), `"msg":"with context"`) {
+		t.Errorf("expected the entry forwarded, actual %s", buffer.String())
+	}
+}
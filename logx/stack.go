@@ -0,0 +1,162 @@
+package logx
+
+import (
+	"bytes"
+	"errors"
+	pkgerrors "github.com/pkg/errors"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+var maximumStackDepth = 64
+
+// WithStacktrace captures the goroutine stack of the entries at or above the level, e.g. ErrorLevel.
+func WithStacktrace(level Level) Option {
+	return func(l *LogX) {
+		l.SetStacktraceLevel(level)
+	}
+}
+
+// SetStacktraceLevel is safe to call while logging, children inherit it unless they set their own.
+func (l *LogX) SetStacktraceLevel(level Level) {
+	atomic.StoreInt32(&l.stacktrace, int32(level)+1)
+}
+
+// stacktraceLevel returns the stacktrace level of the logger or the nearest ancestor which has one.
+func (l *LogX) stacktraceLevel() (Level, bool) {
+	for logger := l; logger != nil; logger = logger.parent {
+		if v := atomic.LoadInt32(&logger.stacktrace); v != 0 {
+			return Level(v - 1), true
+		}
+	}
+	return 0, false
+}
+
+// GetStack returns the stack of the calling goroutine, the frames of this package are skipped.
+func GetStack(skip int) []runtime.Frame {
+	pcs := make([]uintptr, maximumStackDepth)
+	depth := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:depth])
+
+	var stack []runtime.Frame
+	for f, again := frames.Next(); again; f, again = frames.Next() {
+		pkg := getPackageName(f.Function)
+		if len(stack) == 0 && (pkg == packageName && !strings.HasSuffix(f.File, "_test.go") || pkg == "log" || pkg == "log/slog") {
+			continue
+		}
+		stack = append(stack, f)
+	}
+	return stack
+}
+
+// WithError adds the error to the entry, formatters render its message, the messages of
+// the errors.Unwrap chain and the stack it carries, e.g. an error created by github.com/pkg/errors.
+func (e *Entry) WithError(err error) *Entry {
+	c := e.WithFields(nil)
+	c.Err = err
+	return c
+}
+
+func (l *LogX) WithError(err error) *Entry {
+	entry := l.getEntry()
+	defer l.releaseEntry(entry)
+	return entry.WithError(err)
+}
+
+// ErrorChain returns the messages of err and the errors it wraps, a message which repeats
+// the previous one is skipped, e.g. the stack wrapper of github.com/pkg/errors.
+func ErrorChain(err error) []string {
+	var chain []string
+	for ; err != nil; err = errors.Unwrap(err) {
+		msg := safeString(err.Error)
+		if len(chain) > 0 && chain[len(chain)-1] == msg {
+			continue
+		}
+		chain = append(chain, msg)
+	}
+	return chain
+}
+
+type stackTracer interface {
+	StackTrace() pkgerrors.StackTrace
+}
+
+// ErrorStack returns the stack carried by the innermost error of the chain, which is the
+// closest to where the error was created.
+func ErrorStack(err error) []runtime.Frame {
+	var trace pkgerrors.StackTrace
+	for ; err != nil; err = errors.Unwrap(err) {
+		if t, ok := err.(stackTracer); ok {
+			trace = t.StackTrace()
+		}
+	}
+	if len(trace) == 0 {
+		return nil
+	}
+
+	pcs := make([]uintptr, len(trace))
+	for i, f := range trace {
+		pcs[i] = uintptr(f)
+	}
+	frames := runtime.CallersFrames(pcs)
+	var stack []runtime.Frame
+	for f, again := frames.Next(); again; f, again = frames.Next() {
+		stack = append(stack, f)
+	}
+	return stack
+}
+
+// frameString formats the frame as "function file:line".
+func frameString(f runtime.Frame) string {
+	return f.Function + " " + f.File + ":" + strconv.Itoa(f.Line)
+}
+
+func stackStrings(stack []runtime.Frame) []string {
+	lines := make([]string, len(stack))
+	for i, f := range stack {
+		lines[i] = frameString(f)
+	}
+	return lines
+}
+
+// writeStackText writes the error chain and the stacks of the entry as indented lines:
+//
+//	caused by: open app.yaml: no such file or directory
+//	error stack:
+//		main.load
+//			/src/main.go:28
+//	stack:
+//		main.main
+//			/src/main.go:12
+func writeStackText(buffer *bytes.Buffer, entry *Entry) {
+	if entry.Err != nil {
+		chain := ErrorChain(entry.Err)
+		for i := 1; i < len(chain); i++ {
+			buffer.WriteString("\tcaused by: ")
+			buffer.WriteString(chain[i])
+			buffer.WriteByte('\n')
+		}
+		writeFrames(buffer, "error stack", ErrorStack(entry.Err))
+	}
+	writeFrames(buffer, "stack", entry.Stack)
+}
+
+func writeFrames(buffer *bytes.Buffer, title string, stack []runtime.Frame) {
+	if len(stack) == 0 {
+		return
+	}
+	buffer.WriteByte('\t')
+	buffer.WriteString(title)
+	buffer.WriteString(":\n")
+	for _, f := range stack {
+		buffer.WriteString("\t\t")
+		buffer.WriteString(f.Function)
+		buffer.WriteString("\n\t\t\t")
+		buffer.WriteString(f.File)
+		buffer.WriteByte(':')
+		buffer.WriteString(strconv.Itoa(f.Line))
+		buffer.WriteByte('\n')
+	}
+}
@@ -0,0 +1,98 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestStacktraceLevel(t *testing.T) {
+	capture := &captureHook{}
+	logger := NewLogx("test", WithOutput(nil), WithHook(capture), WithStacktrace(ErrorLevel))
+
+	logger.Warn("no stack")
+	logger.Named("child").Error("with stack")
+	if len(capture.entries) != 2 {
+		t.Fatalf("expected 2 entries, actual %d", len(capture.entries))
+	}
+	if capture.entries[0].Stack != nil {
+		t.Errorf("expected no stack below the stacktrace level")
+	}
+	stack := capture.entries[1].Stack
+	if len(stack) == 0 || !strings.HasSuffix(stack[0].Function, "TestStacktraceLevel") {
+		t.Errorf("expected the stack starts at the caller, actual %v", stack)
+	}
+}
+
+func TestWithError(t *testing.T) {
+	cause := errors.New("no such file")
+	err := fmt.Errorf("load config: %w", errors.Wrap(cause, "open app.yaml"))
+
+	entry := newTestEntry(Fields{"user": "tom"}).WithError(err)
+	entry.Level = ErrorLevel
+	entry.Message = "failed"
+	if chain := ErrorChain(err); len(chain) != 3 || chain[2] != "no such file" {
+		t.Errorf("expected the unwrap chain, actual %q", chain)
+	}
+
+	data, err := NewTextFormatter(entry.Logger, false).Format(entry)
+	if err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+	text := string(data)
+	for _, s := range []string{
+		"failed user=tom error=\"load config: open app.yaml: no such file\"\n",
+		"\tcaused by: open app.yaml: no such file\n",
+		"\tcaused by: no such file\n",
+		"\terror stack:\n\t\tgithub.com/xiaorui77/goutils/logx.TestWithError\n",
+	} {
+		if !strings.Contains(text, s) {
+			t.Errorf("expected %q in %q", s, text)
+		}
+	}
+
+	data, err = NewJSONFormatter().Format(entry)
+	if err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+	var actual struct {
+		Error struct {
+			Msg   string   `json:"msg"`
+			Chain []string `json:"chain"`
+			Stack []string `json:"stack"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &actual); err != nil {
+		t.Fatalf("invalid json %q: %v", data, err)
+	}
+	if actual.Error.Msg != "load config: open app.yaml: no such file" || len(actual.Error.Chain) != 2 ||
+		len(actual.Error.Stack) == 0 || !strings.HasPrefix(actual.Error.Stack[0], "github.com/xiaorui77/goutils/logx.TestWithError ") {
+		t.Errorf("unexpected error object %s", data)
+	}
+
+	if entry.AllFields()["error"] != "load config: open app.yaml: no such file" {
+		t.Errorf("expected the error in AllFields, actual %v", entry.AllFields())
+	}
+}
+
+func TestStackJSON(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := NewLogx("test", WithOutput(&buffer), WithFormatter(NewJSONFormatter()), WithStacktrace(ErrorLevel))
+
+	logger.WithError(os.ErrNotExist).Error("failed")
+	var actual map[string]interface{}
+	if err := json.Unmarshal(buffer.Bytes(), &actual); err != nil {
+		t.Fatalf("invalid json %q: %v", buffer.Bytes(), err)
+	}
+	if e, ok := actual["error"].(map[string]interface{}); !ok || e["msg"] != "file does not exist" || e["stack"] != nil {
+		t.Errorf("unexpected error %v", actual["error"])
+	}
+	if stack, ok := actual["stacktrace"].([]interface{}); !ok || len(stack) == 0 ||
+		!strings.HasPrefix(stack[0].(string), "github.com/xiaorui77/goutils/logx.TestStackJSON ") {
+		t.Errorf("expected the stacktrace array, actual %v", actual["stacktrace"])
+	}
+}
@@ -0,0 +1,134 @@
+package logx
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"strings"
+	"sync"
+)
+
+// maxLineSize is the longest line buffered by Writer, a longer line is logged in pieces.
+const maxLineSize = 64 * 1024
+
+// RedirectStdLog sends the output of the standard log package to the std logger at the level,
+// the prefix, date, time and file of log are stripped from the message. It returns a func which
+// restores the previous output of log.
+func RedirectStdLog(level Level) func() {
+	return redirectStdLog(nil, level)
+}
+
+// RedirectStdLog sends the output of the standard log package to the logger, see RedirectStdLog.
+func (l *LogX) RedirectStdLog(level Level) func() {
+	return redirectStdLog(l, level)
+}
+
+func redirectStdLog(l *LogX, level Level) func() {
+	out := log.Writer()
+	log.SetOutput(&stdLogWriter{logger: l, level: level})
+	return func() {
+		log.SetOutput(out)
+	}
+}
+
+// stdLogWriter receives one line per Write from the log package, a nil logger is the current std logger.
+type stdLogWriter struct {
+	logger *LogX
+	level  Level
+}
+
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	logger := w.logger
+	if logger == nil {
+		logger = std
+	}
+	if logger.IsLevelEnabled(w.level) {
+		logger.Log(1, w.level, stripStdLogHeader(string(p), log.Flags(), log.Prefix()))
+	}
+	return len(p), nil
+}
+
+// stripStdLogHeader removes what log.Logger writes around the message, the caller
+// is reported by GetCaller instead, which skips the frames of the log package.
+func stripStdLogHeader(line string, flags int, prefix string) string {
+	line = strings.TrimSuffix(line, "\n")
+	if flags&log.Lmsgprefix == 0 {
+		line = strings.TrimPrefix(line, prefix)
+	}
+	if flags&log.Ldate != 0 && len(line) >= len("2006/01/02 ") {
+		line = line[len("2006/01/02 "):]
+	}
+	if flags&(log.Ltime|log.Lmicroseconds) != 0 {
+		n := len("15:04:05 ")
+		if flags&log.Lmicroseconds != 0 {
+			n += len(".000000")
+		}
+		if len(line) >= n {
+			line = line[n:]
+		}
+	}
+	if flags&(log.Lshortfile|log.Llongfile) != 0 {
+		if i := strings.Index(line, ": "); i >= 0 {
+			line = line[i+2:]
+		}
+	}
+	if flags&log.Lmsgprefix != 0 {
+		line = strings.TrimPrefix(line, prefix)
+	}
+	return line
+}
+
+// Writer returns a writer which logs every line written to it at the level, e.g. as the stderr of a
+// subprocess. Empty lines are skipped, Close logs the last line if it isn't terminated by '\n'.
+func (l *LogX) Writer(level Level) io.WriteCloser {
+	return &lineWriter{logger: l, level: level}
+}
+
+func Writer(level Level) io.WriteCloser {
+	return std.Writer(level)
+}
+
+type lineWriter struct {
+	logger *LogX
+	level  Level
+
+	mu     sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.buffer.Write(p)
+			if w.buffer.Len() >= maxLineSize {
+				w.flush()
+			}
+			break
+		}
+		w.buffer.Write(p[:i])
+		w.flush()
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// flush logs the buffered line, w.mu is held.
+func (w *lineWriter) flush() {
+	line := strings.TrimSuffix(w.buffer.String(), "\r")
+	w.buffer.Reset()
+	if line != "" {
+		w.logger.Log(1, w.level, line)
+	}
+}
+
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flush()
+	return nil
+}
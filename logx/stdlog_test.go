@@ -0,0 +1,60 @@
+package logx
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestRedirectStdLog(t *testing.T) {
+	flags, prefix := log.Flags(), log.Prefix()
+	defer func() {
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	}()
+
+	capture := &captureHook{}
+	logger := NewLogx("test", WithOutput(nil), WithHook(capture), WithReportCaller(true))
+	restore := logger.RedirectStdLog(WarnLevel)
+
+	log.SetPrefix("[lib] ")
+	log.SetFlags(log.LstdFlags | log.Lmicroseconds | log.Lshortfile)
+	log.Printf("first: %d", 1)
+	log.SetFlags(log.Ltime | log.Llongfile | log.Lmsgprefix)
+	log.Print("second\n")
+	log.SetFlags(0)
+	log.Println("third", "line")
+	restore()
+	log.SetOutput(&strings.Builder{})
+	log.Print("not redirected")
+
+	expected := []string{"first: 1", "second", "third line"}
+	if messages := capture.messages(); fmt.Sprint(messages) != fmt.Sprint(expected) {
+		t.Errorf("expected %q, actual %q", expected, messages)
+	}
+	if capture.entries[0].Level != WarnLevel {
+		t.Errorf("expected warn level, actual %s", capture.entries[0].Level)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	capture := &captureHook{}
+	logger := NewLogx("test", WithOutput(nil), WithHook(capture))
+	w := logger.Writer(ErrorLevel)
+
+	_, _ = w.Write([]byte("first\nsecond\r\n\npart"))
+	_, _ = w.Write([]byte("ial\nlast"))
+	if messages := capture.messages(); len(messages) != 3 {
+		t.Errorf("expected 3 lines before close, actual %q", messages)
+	}
+	_ = w.Close()
+
+	expected := []string{"first", "second", "partial", "last"}
+	if messages := capture.messages(); fmt.Sprint(messages) != fmt.Sprint(expected) {
+		t.Errorf("expected %q, actual %q", expected, messages)
+	}
+	if capture.entries[0].Level != ErrorLevel {
+		t.Errorf("expected error level, actual %s", capture.entries[0].Level)
+	}
+}
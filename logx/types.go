@@ -1,36 +1,180 @@
 package logx
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
 type Fields map[string]interface{}
 
-// Level type
+// Level type, a smaller level is more severe. The built-in levels are 10 apart,
+// so custom levels can be registered between them, see RegisterLevel.
+//
+// The values changed from 0, 1, 2, ... to 0, 10, 20, ... when TraceLevel and the registry were added,
+// levels stored or configured as integers must be converted, e.g. 4 (info) is 40 now.
+// Levels marshaled by name, by MarshalText and in config files, are not affected.
 type Level int
 
 const (
-	PanicLevel Level = iota
+	PanicLevel Level = iota * 10
 	FatalLevel
 	ErrorLevel
 	WarnLevel
 	InfoLevel
 	DebugLevel
+	// TraceLevel is for very chatty logs, e.g. the payloads on the wire.
+	TraceLevel
 )
 
+type levelDesc struct {
+	name  string
+	short string
+	color int
+}
+
+// levelRegistry is replaced on change, so it is read without a lock while logging.
+type levelRegistry struct {
+	levels map[Level]levelDesc
+	names  map[string]Level
+	sorted []Level
+}
+
+var (
+	levelsMu sync.Mutex
+	levels   atomic.Value
+)
+
+func init() {
+	r := &levelRegistry{levels: map[Level]levelDesc{}, names: map[string]Level{"warning": WarnLevel}}
+	for _, d := range []struct {
+		level Level
+		levelDesc
+	}{
+		{PanicLevel, levelDesc{"panic", "PANIC", red}},
+		{FatalLevel, levelDesc{"fatal", "FATAL", red}},
+		{ErrorLevel, levelDesc{"error", "ERROR", red}},
+		{WarnLevel, levelDesc{"warn", "WARN", yellow}},
+		{InfoLevel, levelDesc{"info", "INFO", green}},
+		{DebugLevel, levelDesc{"debug", "DEBUG", gray}},
+		{TraceLevel, levelDesc{"trace", "TRACE", blue}},
+	} {
+		r.add(d.level, d.levelDesc)
+	}
+	levels.Store(r)
+}
+
+func (r *levelRegistry) add(level Level, desc levelDesc) {
+	r.levels[level] = desc
+	r.names[desc.name] = level
+	i := sort.Search(len(r.sorted), func(i int) bool { return r.sorted[i] > level })
+	r.sorted = append(r.sorted, 0)
+	copy(r.sorted[i+1:], r.sorted[i:])
+	r.sorted[i] = level
+}
+
+func loadLevels() *levelRegistry {
+	return levels.Load().(*levelRegistry)
+}
+
+// RegisterLevel adds a custom level, e.g. RegisterLevel(35, "notice", "NOTIC", 36) is between warn and info.
+// The name is case-insensitive for ParseLevel and lower case for String, short is displayed by the text
+// formatter, padded to 5 characters, and color is an ANSI color code. Log it by Log(level, ...).
+func RegisterLevel(level Level, name, short string, color int) error {
+	name = strings.ToLower(name)
+	if level < PanicLevel {
+		return fmt.Errorf("logx: level %d is more severe than panic", level)
+	}
+	if name == "" {
+		return fmt.Errorf("logx: level name is empty")
+	}
+	if short == "" {
+		short = strings.ToUpper(name)
+	}
+
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	old := loadLevels()
+	if desc, ok := old.levels[level]; ok {
+		return fmt.Errorf("logx: level %d is registered as %q", level, desc.name)
+	}
+	if _, ok := old.names[name]; ok {
+		return fmt.Errorf("logx: level name %q is registered", name)
+	}
+
+	r := old.copy()
+	r.add(level, levelDesc{name: name, short: short, color: color})
+	levels.Store(r)
+	return nil
+}
+
+// unregisterLevel removes a custom level, it's used by tests to restore the registry.
+func unregisterLevel(level Level) {
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	r := loadLevels().copy()
+	delete(r.levels, level)
+	for name, l := range r.names {
+		if l == level {
+			delete(r.names, name)
+		}
+	}
+	for i, l := range r.sorted {
+		if l == level {
+			r.sorted = append(r.sorted[:i], r.sorted[i+1:]...)
+			break
+		}
+	}
+	levels.Store(r)
+}
+
+func (r *levelRegistry) copy() *levelRegistry {
+	c := &levelRegistry{
+		levels: make(map[Level]levelDesc, len(r.levels)+1),
+		names:  make(map[string]Level, len(r.names)+1),
+		sorted: append([]Level(nil), r.sorted...),
+	}
+	for k, v := range r.levels {
+		c.levels[k] = v
+	}
+	for k, v := range r.names {
+		c.names[k] = v
+	}
+	return c
+}
+
+// AllLevels returns a copy of the registered levels from the most severe.
+func AllLevels() []Level {
+	return append([]Level(nil), loadLevels().sorted...)
+}
+
+// LevelsFrom returns a copy of the registered levels at or above level, e.g. for Hook.Levels.
+func LevelsFrom(level Level) []Level {
+	sorted := loadLevels().sorted
+	i := sort.Search(len(sorted), func(i int) bool { return sorted[i] > level })
+	return append([]Level(nil), sorted[:i]...)
+}
+
 func (l Level) String() string {
-	switch l {
-	case PanicLevel:
-		return "panic"
-	case FatalLevel:
-		return "fatal"
-	case ErrorLevel:
-		return "error"
-	case WarnLevel:
-		return "warn"
-	case InfoLevel:
-		return "info"
-	case DebugLevel:
-		return "debug"
-	default:
-		return "unknown"
+	if desc, ok := loadLevels().levels[l]; ok {
+		return desc.name
+	}
+	return "unknown"
+}
+
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+func (l *Level) UnmarshalText(text []byte) error {
+	level, ok := LookupLevel(string(text))
+	if !ok {
+		return fmt.Errorf("logx: unknown level %q", text)
 	}
+	*l = level
+	return nil
 }
 
 type Formatter interface {
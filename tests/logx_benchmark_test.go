@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"github.com/xiaorui77/goutils/logx"
 	"testing"
+	"time"
 )
 
 // result: 580 ns/op on Apple M1
@@ -41,3 +42,25 @@ func BenchmarkGlobalInfoWithCaller(b *testing.B) {
 		logx.Infof("Hello %s", "hello")
 	}
 }
+
+// fields by map, every WithField allocates a new Fields map
+func BenchmarkWithFields(b *testing.B) {
+	var buffer bytes.Buffer
+	logger := logx.NewLogx("test", logx.WithOutput(&buffer), logx.WithFormatter(logx.NewJSONFormatter()))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buffer.Reset()
+		logger.WithFields(logx.Fields{"path": "/hello", "status": 200, "cost": time.Millisecond}).Info("request done")
+	}
+}
+
+// typed fields are stored in a slice and encoded without boxing
+func BenchmarkInfow(b *testing.B) {
+	var buffer bytes.Buffer
+	logger := logx.NewLogx("test", logx.WithOutput(&buffer), logx.WithFormatter(logx.NewJSONFormatter()))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buffer.Reset()
+		logger.Infow("request done", logx.String("path", "/hello"), logx.Int("status", 200), logx.Duration("cost", time.Millisecond))
+	}
+}